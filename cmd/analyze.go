@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -16,15 +20,27 @@ var (
 	showPatterns bool
 	outputFormat string
 	saveResults  string
+	minLength    int
+	noCache      bool
+	maxCost      float64
 )
 
 // analyzeCmd represents the analyze command
 var analyzeCmd = &cobra.Command{
-	Use:   "analyze [binary_path]",
-	Short: "Analyze a binary file to detect its version",
+	Use:   "analyze [binary_path...]",
+	Short: "Analyze binary files to detect their versions",
 	Long: `Analyze scans a binary file using regex patterns to find potential version 
 strings, then uses AI to determine the most likely version.
 
+Several binaries can be analyzed in one run, which ends with the total token
+usage and estimated cost of the AI calls. With --max-cost, no further AI calls
+are made once the budget is used up; the remaining binaries are reported with
+their candidates only.
+
+Universal (fat) Mach-O binaries are split into their slices and every slice is
+reported separately. --save writes all results of a run to one file, as a
+JSON array or YAML list when there is more than one.
+
 The command supports various output formats and can save results to a file.`,
 	Example: `  # Basic analysis
   binary-version-analyzer analyze /usr/bin/ls
@@ -36,8 +52,11 @@ The command supports various output formats and can save results to a file.`,
   binary-version-analyzer analyze /usr/bin/python3 --show-config --show-patterns
 
   # Save results to JSON file
-  binary-version-analyzer analyze /usr/bin/git --output json --save results.json`,
-	Args: cobra.ExactArgs(1),
+  binary-version-analyzer analyze /usr/bin/git --output json --save results.json
+
+  # Analyze several binaries, spending at most 5 cents on AI calls
+  binary-version-analyzer analyze /usr/bin/* --max-cost 0.05`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: runAnalyze,
 }
 
@@ -49,28 +68,32 @@ func init() {
 	analyzeCmd.Flags().BoolVar(&showPatterns, "show-patterns", false, "Display pattern information")
 	analyzeCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text, json, yaml)")
 	analyzeCmd.Flags().StringVar(&saveResults, "save", "", "Save results to file")
+	analyzeCmd.Flags().IntVar(&minLength, "min-length", internal.DefaultMinStringLength, "Minimum length of printable strings to scan")
+	analyzeCmd.Flags().BoolVar(&noCache, "no-cache", false, "Always ask the AI provider instead of reusing cached verdicts")
+	analyzeCmd.Flags().Float64Var(&maxCost, "max-cost", 0, "Stop AI calls once their estimated cost in USD reaches this budget (0 for no limit)")
 
 	// Mark binary path as required
 	analyzeCmd.MarkFlagRequired("binary_path")
 }
 
 func runAnalyze(cmd *cobra.Command, args []string) error {
-	binaryPath := args[0]
-
-	// Check if file exists
-	if _, err := os.Stat(binaryPath); os.IsNotExist(err) {
-		return fmt.Errorf("❌ Error: File %s does not exist", binaryPath)
+	if maxCost < 0 {
+		return fmt.Errorf("❌ Error: --max-cost must not be negative, got: %g", maxCost)
 	}
 
-	if verbose {
-		fmt.Printf("🔍 Starting analysis of: %s\n", binaryPath)
+	// Load configuration from the config file and environment (with CLI overrides)
+	fileConfig, err := loadFileConfig()
+	if err != nil {
+		return fmt.Errorf("❌ Error loading config file: %v", err)
 	}
-
-	// Load configuration from environment (with CLI overrides)
-	config, err := providers.LoadConfigFromEnv()
+	config, err := loadAIConfig(fileConfig)
 	if err != nil {
 		return fmt.Errorf("❌ Error loading configuration: %v", err)
 	}
+	prices, err := fileConfig.PriceTable()
+	if err != nil {
+		return fmt.Errorf("❌ Error loading config file: %v", err)
+	}
 
 	// Create AI provider using factory
 	factory := providers.NewAIFactory()
@@ -79,12 +102,30 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("❌ Error creating AI provider: %v", err)
 	}
 
+	// Reuse earlier verdicts for the same binary; the offline ranker is free to rerun
+	useCache := !noCache && fileConfig.CacheEnabled() && !(providers.IsOfflineProvider(config.Provider) && len(config.Fallbacks) == 0)
+	if useCache {
+		cache, err := fileConfig.OpenCache()
+		if err != nil {
+			return fmt.Errorf("❌ Error opening verdict cache: %v", err)
+		}
+		aiProvider = providers.NewCachingProvider(aiProvider, cache, providers.CacheIdentity(config))
+	}
+
 	// Create analyzer
 	analyzer := internal.NewBinaryAnalyzer(aiProvider)
+	if err := fileConfig.ApplyScanSettings(analyzer); err != nil {
+		return fmt.Errorf("❌ Error applying config file: %v", err)
+	}
+	if cmd.Flags().Changed("min-length") {
+		analyzer.SetMinStringLength(minLength)
+	}
 
 	// Display information
-	fmt.Printf("🔍 Analyzing binary: %s\n", binaryPath)
 	fmt.Printf("🤖 Using AI Provider: %s\n", aiProvider.GetProviderName())
+	if maxCost > 0 {
+		fmt.Printf("💸 Cost budget: $%.4f\n", maxCost)
+	}
 
 	if showConfig {
 		providers.PrintConfigInfo(config)
@@ -99,67 +140,448 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
+	// Every AI call of the run is metered, so the budget spans all binaries
+	meter := providers.NewUsageMeter(prices, maxCost)
+	ctx := providers.WithUsageMeter(cmd.Context(), meter)
+	run := &analyzeRun{
+		analyzer: analyzer,
+		provider: aiProvider,
+		config:   config,
+		useCache: useCache,
+		meter:    meter,
+	}
+
+	failed := 0
+	var results []*internal.AnalysisResult
+	for i, binaryPath := range args {
+		if len(args) > 1 {
+			fmt.Printf("\n━━━ [%d/%d] %s\n", i+1, len(args), binaryPath)
+		}
+		binaryResults, err := run.analyzeBinary(ctx, binaryPath)
+		if ctx.Err() != nil || (err != nil && len(args) == 1) {
+			return err
+		}
+		if err != nil {
+			fmt.Println(err)
+			failed++
+		}
+		results = append(results, binaryResults...)
+	}
+
+	// One file holds every result of the run
+	if saveResults != "" && len(results) > 0 {
+		if err := internal.SaveResults(results, outputFormat, saveResults); err != nil {
+			return fmt.Errorf("❌ Error outputting result: %v", err)
+		}
+	}
+
+	if len(args) > 1 {
+		fmt.Printf("\n📦 Analyzed %d binaries, %d failed\n", len(args), failed)
+		if total := meter.Total(); total.Calls > 0 {
+			fmt.Printf("💰 Total AI usage: %s\n", total)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("❌ %d of %d binaries could not be analyzed", failed, len(args))
+	}
+	return nil
+}
+
+// analyzeRun holds what the binaries of one analyze invocation share
+type analyzeRun struct {
+	analyzer *internal.BinaryAnalyzer
+	provider providers.AIProvider
+	config   *providers.AIConfig
+	useCache bool
+	meter    *providers.UsageMeter
+}
+
+// analyzeBinary analyzes one binary and returns its results: one per slice of
+// a universal Mach-O binary, otherwise a single one
+func (r *analyzeRun) analyzeBinary(ctx context.Context, binaryPath string) ([]*internal.AnalysisResult, error) {
+	// Check if file exists
+	if _, err := os.Stat(binaryPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("❌ Error: File %s does not exist", binaryPath)
+	}
+
+	fmt.Printf("🔍 Analyzing binary: %s\n", binaryPath)
+
+	slices, err := r.analyzer.ReadMachO(binaryPath)
+	if err != nil {
+		result, err := r.analyzeTarget(ctx, binaryPath, nil)
+		if result == nil {
+			return nil, err
+		}
+		return []*internal.AnalysisResult{result}, err
+	}
+
+	if len(slices) > 1 {
+		archs := make([]string, len(slices))
+		for i, slice := range slices {
+			archs[i] = slice.Arch
+		}
+		fmt.Printf("🍎 Universal binary with %d slices: %s\n", len(slices), strings.Join(archs, ", "))
+	}
+
+	var results []*internal.AnalysisResult
+	for _, slice := range slices {
+		if len(slices) > 1 {
+			fmt.Printf("\n── Slice %s at 0x%x\n", slice.Arch, slice.Offset)
+		}
+		result, err := r.analyzeTarget(ctx, binaryPath, slice)
+		if err != nil {
+			return results, err
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// analyzeTarget scans a binary, or one slice of a Mach-O binary, asks the AI
+// provider for its version and prints the result. It returns no result when
+// nothing looks like a version.
+func (r *analyzeRun) analyzeTarget(ctx context.Context, binaryPath string, slice *internal.MachOSlice) (*internal.AnalysisResult, error) {
 	fmt.Println("📊 Scanning for version candidates...")
 
 	// Scan the binary for version candidates
-	candidates, err := analyzer.ScanBinary(binaryPath)
+	analyzer := r.analyzer
+	var candidates []internal.Candidate
+	var err error
+	if slice != nil {
+		candidates, err = analyzer.ScanMachOSlice(ctx, binaryPath, slice)
+	} else {
+		candidates, err = analyzer.ScanBinaryContext(ctx, binaryPath)
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("🛑 Scan cancelled")
+	}
 	if err != nil {
-		return fmt.Errorf("❌ Error scanning binary: %v", err)
+		return nil, fmt.Errorf("❌ Error scanning binary: %v", err)
 	}
 
-	if len(candidates) == 0 {
+	// Go binaries carry exact module versions in their build info
+	var goInfo *internal.GoBuildInfo
+	if slice != nil {
+		goInfo, err = analyzer.ReadMachOGoBuildInfo(binaryPath, slice)
+	} else {
+		goInfo, err = analyzer.ReadGoBuildInfo(binaryPath)
+	}
+	if err != nil && verbose {
+		fmt.Println("💡 No Go build info found, relying on pattern scan")
+	}
+	if goInfo != nil {
+		printGoBuildInfo(goInfo)
+		candidates = goInfo.FilterDependencyVersions(candidates)
+	}
+
+	var deps *internal.ELFDependencies
+	var peInfo *internal.PEVersionInfo
+	var assembly *internal.DotNetAssembly
+	if slice != nil {
+		// Mach-O load commands carry the source and library versions
+		printMachOSlice(slice)
+		candidates = internal.PrependCandidates(slice.Candidates(), slice.FilterDependencyVersions(candidates))
+	} else {
+		// Symbol versions such as GLIBC_2.34 name the libraries an ELF file needs
+		deps, err = analyzer.ReadELFDependencies(binaryPath)
+		if err != nil && verbose {
+			fmt.Printf("💡 No dynamic dependencies found (%v)\n", err)
+		}
+		if deps != nil {
+			printELFDependencies(deps)
+			candidates = deps.FilterDependencyVersions(candidates)
+		}
+
+		// Windows executables and DLLs declare their versions in a resource
+		peInfo, err = analyzer.ReadPEVersionInfo(binaryPath)
+		if err != nil && verbose {
+			fmt.Printf("💡 No PE version resource found (%v)\n", err)
+		}
+		if peInfo != nil {
+			printPEVersionInfo(peInfo)
+			candidates = internal.PrependCandidates(peInfo.Candidates(), candidates)
+		}
+
+		// .NET assemblies carry their identity and references in CLR metadata
+		assembly, err = analyzer.ReadDotNetAssembly(binaryPath)
+		if err != nil && verbose && peInfo != nil {
+			fmt.Printf("💡 No .NET metadata found (%v)\n", err)
+		}
+		if assembly != nil {
+			printDotNetAssembly(assembly)
+			candidates = internal.PrependCandidates(assembly.Candidates(), assembly.FilterReferenceVersions(candidates))
+		}
+	}
+
+	// Rust binaries built with cargo auditable embed their dependency tree
+	rustInfo, err := analyzer.ReadRustInfo(binaryPath)
+	if err != nil && verbose {
+		fmt.Printf("💡 No Rust metadata found (%v)\n", err)
+	}
+	if rustInfo != nil {
+		printRustInfo(rustInfo)
+		candidates = internal.PrependCandidates(rustInfo.Candidates(), rustInfo.FilterDependencyVersions(candidates))
+	}
+
+	if len(candidates) == 0 && (goInfo == nil || !goInfo.HasReleaseVersion()) {
 		fmt.Println("❌ No version candidates found in the binary.")
 		fmt.Println("💡 Try running 'binary-version-analyzer patterns list' to see what patterns are used")
-		return nil
+		return nil, nil
+	}
+
+	if len(candidates) > 0 {
+		fmt.Printf("\n✅ Found %d potential version candidates:\n", len(candidates))
+		for i, candidate := range candidates {
+			fmt.Printf("   %d. %s\n", i+1, candidate)
+			if verbose {
+				fmt.Printf("      %s (Priority: %d) at 0x%x, seen %d time(s), scheme %s\n", candidate.Pattern, candidate.Priority, candidate.Offset, candidate.Count, candidate.Scheme)
+				fmt.Printf("      %q\n", candidate.Context)
+			}
+		}
 	}
 
-	fmt.Printf("\n✅ Found %d potential version candidates:\n", len(candidates))
-	for i, candidate := range candidates {
-		fmt.Printf("   %d. %s\n", i+1, candidate)
+	binaryName := filepath.Base(binaryPath)
+	result := &internal.AnalysisResult{
+		BinaryPath:    binaryPath,
+		BinaryName:    binaryName,
+		Candidates:    candidates,
+		GoBuildInfo:   goInfo,
+		PEVersionInfo: peInfo,
+		DotNet:        assembly,
+		MachO:         slice,
+		Rust:          rustInfo,
+		Dependencies:  deps,
+		PatternCount:  analyzer.GetPatternCount(),
 	}
 
-	fmt.Printf("\n🧠 Analyzing with %s AI...\n", aiProvider.GetProviderName())
+	if goInfo != nil && goInfo.HasReleaseVersion() {
+		// The module version is authoritative, no need to ask the AI
+		fmt.Printf("\n🏷️  Using Go module version %s from build info\n", goInfo.Version)
+		result.SetVersion(goInfo.ReleaseVersion())
+		result.VersionSource = internal.SourceGoBuildInfo
+	} else if rustInfo != nil && rustInfo.HasCrateVersion() {
+		// So is the root crate version of a cargo auditable binary
+		fmt.Printf("\n🏷️  Using crate version %s %s from cargo auditable data\n", rustInfo.Crate, rustInfo.Version)
+		result.SetVersion(rustInfo.Version)
+		result.VersionSource = internal.SourceRustAudit
+	} else if err := r.analyzeWithAI(ctx, binaryPath, result); err != nil {
+		return nil, err
+	}
 
-	// Analyze with AI
-	binaryName := filepath.Base(binaryPath)
-	version, err := analyzer.AnalyzeWithAI(binaryName, candidates)
-	if err != nil {
-		return fmt.Errorf("❌ Error analyzing with AI: %v", err)
+	if slice != nil {
+		binaryName = fmt.Sprintf("%s (%s)", binaryName, slice.Arch)
+	}
+	fmt.Printf("\n🎯 Most likely version for %s: %s\n", binaryName, result.Version)
+	if result.Scheme != "" {
+		fmt.Printf("📐 Version scheme: %s\n", result.Scheme)
+	}
+	if result.Product != "" {
+		fmt.Printf("📦 Product: %s\n", result.Product)
 	}
+	if result.Confidence > 0 {
+		fmt.Printf("📈 Confidence: %.0f%%\n", result.Confidence*100)
+	}
+	if result.Rationale != "" {
+		fmt.Printf("💬 Rationale: %s\n", result.Rationale)
+	}
+	if result.Usage != nil {
+		fmt.Printf("💰 AI usage: %s\n", result.Usage)
+	}
+	return result, nil
+}
 
-	// Create result
-	result := &internal.AnalysisResult{
-		BinaryPath:   binaryPath,
-		BinaryName:   binaryName,
-		Version:      version,
-		Candidates:   candidates,
-		Provider:     aiProvider.GetProviderName(),
-		Model:        config.Model,
-		PatternCount: analyzer.GetPatternCount(),
+// analyzeWithAI asks the provider to pick the binary's version from the
+// candidates. Once the cost budget is used up, only the candidates are reported.
+func (r *analyzeRun) analyzeWithAI(ctx context.Context, binaryPath string, result *internal.AnalysisResult) error {
+	fmt.Printf("\n🧠 Analyzing with %s AI...\n", r.provider.GetProviderName())
+
+	req := internal.NewRequest(result.BinaryName, result.Candidates)
+	if r.useCache {
+		var err error
+		if result.SHA256, err = internal.FileSHA256(ctx, binaryPath); err != nil && ctx.Err() == nil {
+			return fmt.Errorf("❌ Error hashing binary: %v", err)
+		}
+		req.BinarySHA256 = result.SHA256
+	}
+
+	before := r.meter.Total()
+	response, err := r.analyzer.AnalyzeRequestContext(ctx, req)
+	if usage := r.meter.Total().Sub(before); usage.Calls > 0 {
+		result.Usage = &usage
 	}
 
-	// Output result
-	if err := outputResult(result, outputFormat, saveResults); err != nil {
-		return fmt.Errorf("❌ Error outputting result: %v", err)
+	var unverified *providers.UnverifiedVersionError
+	var overBudget *providers.BudgetExceededError
+	switch {
+	case ctx.Err() != nil:
+		return fmt.Errorf("🛑 AI analysis cancelled")
+	case errors.As(err, &overBudget):
+		// Keep the candidates so the run still produces a usable report
+		fmt.Printf("💸 %v, listing candidates only\n", err)
+		result.Version = providers.UnknownVersion
+		result.VersionSource = internal.SourceCandidates
+		return nil
+	case errors.As(err, &unverified):
+		// Report unknown rather than a version the AI made up
+		fmt.Printf("⚠️  %v\n", err)
+		result.Version = providers.UnknownVersion
+	case err != nil:
+		return fmt.Errorf("❌ Error analyzing with AI: %v", err)
+	default:
+		result.SetResponse(response)
+		for _, skipped := range response.Skipped {
+			fmt.Printf("⏭️  Skipped %s: %s\n", skipped.Provider, skipped.Reason)
+		}
+		if len(response.Skipped) > 0 {
+			fmt.Printf("🤖 Answered by %s\n", response.ProviderName)
+		}
+		if response.Cached {
+			fmt.Printf("💾 Using cached verdict from %s\n", response.ProviderName)
+		}
 	}
 
-	fmt.Printf("\n🎯 Most likely version for %s: %s\n", binaryName, version)
+	result.VersionSource = internal.SourceAI
+	if result.Provider == "" {
+		result.Provider = r.provider.GetProviderName()
+	}
+	if result.Model == "" {
+		result.Model = r.config.Model
+	}
 	return nil
 }
 
-func outputResult(result *internal.AnalysisResult, format, saveFile string) error {
-	if saveFile == "" {
-		return nil // No saving required
+func printGoBuildInfo(info *internal.GoBuildInfo) {
+	fmt.Println("\n🐹 Go build info:")
+	fmt.Printf("   Module: %s\n", info.Module)
+	fmt.Printf("   Version: %s\n", info.Version)
+	fmt.Printf("   Go Version: %s\n", info.GoVersion)
+	if info.VCSRevision != "" {
+		fmt.Printf("   VCS Revision: %s\n", info.VCSRevision)
 	}
+	if info.VCSTime != "" {
+		fmt.Printf("   VCS Time: %s\n", info.VCSTime)
+	}
+	fmt.Printf("   Dependencies: %d\n", len(info.Dependencies))
+	if verbose {
+		for _, dep := range info.Dependencies {
+			fmt.Printf("     • %s %s\n", dep.Path, dep.Version)
+		}
+	}
+}
 
-	switch format {
-	case "json":
-		return result.SaveAsJSON(saveFile)
-	case "yaml":
-		return result.SaveAsYAML(saveFile)
-	case "text":
-		return result.SaveAsText(saveFile)
-	default:
-		return fmt.Errorf("unsupported output format: %s", format)
+func printPEVersionInfo(info *internal.PEVersionInfo) {
+	fmt.Println("\n🪟 PE version info:")
+	if info.ProductName != "" {
+		fmt.Printf("   Product: %s\n", info.ProductName)
+	}
+	if info.CompanyName != "" {
+		fmt.Printf("   Company: %s\n", info.CompanyName)
+	}
+	if info.ProductVersion != "" {
+		fmt.Printf("   Product Version: %s\n", info.ProductVersion)
+	}
+	if info.FileVersion != "" {
+		fmt.Printf("   File Version: %s\n", info.FileVersion)
+	}
+	if info.FixedFileVersion != "" {
+		fmt.Printf("   Fixed File Version: %s\n", info.FixedFileVersion)
+	}
+	if verbose {
+		for _, key := range sortedKeys(info.Strings) {
+			fmt.Printf("     • %s: %s\n", key, info.Strings[key])
+		}
+	}
+}
+
+func printDotNetAssembly(assembly *internal.DotNetAssembly) {
+	fmt.Println("\n🟣 .NET assembly:")
+	fmt.Printf("   Name: %s\n", assembly.Name)
+	fmt.Printf("   Assembly Version: %s\n", assembly.Version)
+	if assembly.FileVersion != "" {
+		fmt.Printf("   File Version: %s\n", assembly.FileVersion)
+	}
+	if assembly.InformationalVersion != "" {
+		fmt.Printf("   Informational Version: %s\n", assembly.InformationalVersion)
+	}
+	if assembly.TargetFramework != "" {
+		fmt.Printf("   Target Framework: %s\n", assembly.TargetFramework)
+	}
+	fmt.Printf("   References: %d\n", len(assembly.References))
+	if verbose {
+		for _, ref := range assembly.References {
+			fmt.Printf("     • %s %s\n", ref.Name, ref.Version)
+		}
+	}
+}
+
+func printELFDependencies(deps *internal.ELFDependencies) {
+	fmt.Println("\n🔗 Dynamic dependencies:")
+	if deps.SOName != "" {
+		fmt.Printf("   SONAME: %s\n", deps.SOName)
+	}
+	if deps.GLIBC != "" {
+		fmt.Printf("   Minimum glibc: %s\n", deps.GLIBC)
+	}
+	if deps.GLIBCXX != "" {
+		fmt.Printf("   Minimum libstdc++ (GLIBCXX): %s\n", deps.GLIBCXX)
+	}
+	fmt.Printf("   Needed Libraries: %d\n", len(deps.Needed))
+	if verbose {
+		for _, lib := range deps.Needed {
+			fmt.Printf("     • %s\n", lib)
+		}
+		for _, set := range deps.Required {
+			fmt.Printf("     • %s requires %s_%s\n", set.Library, set.Name, set.Minimum)
+		}
+	}
+}
+
+func printRustInfo(info *internal.RustInfo) {
+	fmt.Println("\n🦀 Rust:")
+	if info.Crate != "" {
+		fmt.Printf("   Crate: %s %s\n", info.Crate, info.Version)
+		fmt.Printf("   Dependencies: %d\n", len(info.Dependencies))
+	}
+	if info.RustcVersion != "" {
+		fmt.Printf("   rustc Version: %s\n", info.RustcVersion)
+	}
+	if verbose {
+		for _, dep := range info.Dependencies {
+			fmt.Printf("     • %s %s\n", dep.Name, dep.Version)
+		}
+	}
+}
+
+func printMachOSlice(slice *internal.MachOSlice) {
+	fmt.Printf("\n🍎 Mach-O %s:\n", slice.Label())
+	if slice.SourceVersion != "" {
+		fmt.Printf("   Source Version: %s\n", slice.SourceVersion)
+	}
+	if slice.ID != nil {
+		fmt.Printf("   Install Name: %s %s (compatibility %s)\n", slice.ID.Name, slice.ID.CurrentVersion, slice.ID.CompatibilityVersion)
+	}
+	if slice.Platform != "" {
+		fmt.Printf("   Platform: %s %s (SDK %s)\n", slice.Platform, slice.MinOS, slice.SDK)
+	}
+	fmt.Printf("   Linked Libraries: %d\n", len(slice.Dylibs))
+	if verbose {
+		for _, dylib := range slice.Dylibs {
+			fmt.Printf("     • %s %s\n", dylib.Name, dylib.CurrentVersion)
+		}
+		for _, tool := range slice.Tools {
+			fmt.Printf("     • %s %s\n", tool.Name, tool.Version)
+		}
+	}
+}
+
+// sortedKeys returns the keys of a string map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
+	return keys
 }
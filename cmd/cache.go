@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"binary-version-analyzer/providers"
+)
+
+// cacheCmd represents the cache command group
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and clean the AI verdict cache",
+	Long: `Analyze caches AI verdicts on disk, keyed by the binary's SHA-256, the
+candidate list, the provider and model, and the prompt version, so repeated
+runs over the same binary do not pay for identical AI calls.
+
+The cache lives under $XDG_CACHE_HOME/binary-version-analyzer (override with
+AI_CACHE_DIR or cache.dir in the config file). Verdicts expire after
+AI_CACHE_TTL or cache.ttl, 7 days by default.`,
+	Example: `  # Show how many verdicts are cached
+  binary-version-analyzer cache stats
+
+  # Remove expired verdicts
+  binary-version-analyzer cache prune
+
+  # Remove all verdicts
+  binary-version-analyzer cache clear`,
+}
+
+// cacheStatsCmd shows cache statistics
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the number, age and size of cached verdicts",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheStats,
+}
+
+// cacheClearCmd removes every cached verdict
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached verdicts",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheClear,
+}
+
+// cachePruneCmd removes expired verdicts
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove expired verdicts and shrink the cache to its size cap",
+	Args:  cobra.NoArgs,
+	RunE:  runCachePrune,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+
+	// Add subcommands
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+}
+
+// openCache opens the verdict cache configured by the config file and environment
+func openCache() (*providers.VerdictCache, error) {
+	fileConfig, err := loadFileConfig()
+	if err != nil {
+		return nil, fmt.Errorf("❌ Error loading config file: %v", err)
+	}
+	cache, err := fileConfig.OpenCache()
+	if err != nil {
+		return nil, fmt.Errorf("❌ Error opening verdict cache: %v", err)
+	}
+	return cache, nil
+}
+
+func runCacheStats(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		return fmt.Errorf("❌ Error reading verdict cache: %v", err)
+	}
+
+	fmt.Println("💾 AI verdict cache:")
+	fmt.Printf("   Directory: %s\n", stats.Dir)
+	fmt.Printf("   Entries: %d (%d expired)\n", stats.Entries, stats.Expired)
+	fmt.Printf("   Size: %.1f KiB\n", float64(stats.Size)/1024)
+	if stats.Entries > 0 {
+		fmt.Printf("   Oldest: %s\n", stats.Oldest.Format(time.RFC3339))
+		fmt.Printf("   Newest: %s\n", stats.Newest.Format(time.RFC3339))
+	}
+	if stats.Expired > 0 {
+		fmt.Println("💡 Run 'binary-version-analyzer cache prune' to remove expired verdicts")
+	}
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	removed, err := cache.Clear()
+	if err != nil {
+		return fmt.Errorf("❌ Error clearing verdict cache: %v", err)
+	}
+	fmt.Printf("🧹 Removed %d cached verdict(s) from %s\n", removed, cache.Dir())
+	return nil
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	removed, err := cache.Prune()
+	if err != nil {
+		return fmt.Errorf("❌ Error pruning verdict cache: %v", err)
+	}
+	fmt.Printf("🧹 Pruned %d cached verdict(s) from %s\n", removed, cache.Dir())
+	return nil
+}
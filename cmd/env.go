@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"binary-version-analyzer/providers"
+)
+
+// envCmd represents the env command
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Show the effective AI configuration and where each setting came from",
+	Long: `Env resolves the AI configuration the same way analyze does and prints the
+effective value of every setting together with the source that won.
+
+Settings are resolved with the precedence:
+  flag > provider env (e.g. GROQ_MODEL) > generic env (e.g. AI_MODEL) > profile > default
+
+For a provider chain such as groq,openai,heuristic the settings of each
+fallback are listed with the provider name in front, e.g. openai.model.`,
+	Example: `  # Show the effective configuration
+  binary-version-analyzer env
+
+  # See what a profile and provider would use
+  binary-version-analyzer env --profile openai-accurate --provider openai`,
+	Args: cobra.NoArgs,
+	RunE: runEnv,
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+}
+
+func runEnv(cmd *cobra.Command, args []string) error {
+	fileConfig, err := loadFileConfig()
+	if err != nil {
+		return fmt.Errorf("❌ Error loading config file: %v", err)
+	}
+
+	resolver, err := newConfigResolver(fileConfig)
+	if err != nil {
+		return fmt.Errorf("❌ Error loading configuration: %v", err)
+	}
+
+	config, settings, err := resolver.Resolve()
+	if err != nil {
+		return fmt.Errorf("❌ Error resolving configuration: %v", err)
+	}
+
+	fmt.Printf("🔧 Effective configuration for %s:\n", config.Provider)
+	fmt.Println(strings.Repeat("=", 50))
+	for _, setting := range settings {
+		value := setting.Value
+		name := setting.Name[strings.LastIndex(setting.Name, ".")+1:]
+		switch {
+		case value == "":
+			value = "(not set)"
+		case name == "api_key":
+			value = providers.MaskAPIKey(value)
+		case name == "headers":
+			value = maskHeaderValues(value)
+		}
+		fmt.Printf("   %-28s %-34s %s\n", setting.Name, value, setting.Source)
+	}
+
+	var missing []string
+	for _, member := range append([]*providers.AIConfig{config}, config.Fallbacks...) {
+		if member.APIKey == "" && providers.RequiresAPIKey(member.Provider) {
+			missing = append(missing, fmt.Sprintf("No API key set for %s, export %s", member.Provider, providers.GetProviderSpecificEnvVars(member.Provider)["API_KEY"]))
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Printf("\n⚠️  %s\n", strings.Join(missing, "\n⚠️  "))
+	}
+	return nil
+}
+
+// maskHeaderValues hides header values, which often carry credentials
+func maskHeaderValues(headers string) string {
+	pairs := strings.Split(headers, ",")
+	for i, pair := range pairs {
+		if name, _, ok := strings.Cut(pair, "="); ok {
+			pairs[i] = name + "=***"
+		}
+	}
+	return strings.Join(pairs, ",")
+}
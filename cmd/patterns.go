@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"binary-version-analyzer/patterns"
+	"binary-version-analyzer/versions"
 )
 
 var (
@@ -160,7 +162,7 @@ func runPatternsList(cmd *cobra.Command, args []string) error {
 
 func runPatternsTest(cmd *cobra.Command, args []string) error {
 	if interactive {
-		return runInteractiveTest()
+		return runInteractiveTest(cmd.Context())
 	}
 
 	// Get test string from args or flag
@@ -218,7 +220,8 @@ func runPatternsDocs(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func runInteractiveTest() error {
+// runInteractiveTest reads test strings from stdin until quit, end of input or Ctrl-C
+func runInteractiveTest(ctx context.Context) error {
 	fmt.Println("🎮 Interactive Pattern Testing Mode")
 	fmt.Println(strings.Repeat("=", 40))
 	fmt.Println()
@@ -230,15 +233,31 @@ func runInteractiveTest() error {
 	fmt.Println("  'validate' - Validate all patterns")
 	fmt.Println()
 
-	scanner := bufio.NewScanner(os.Stdin)
+	// Lines are read in the background so Ctrl-C is noticed while waiting for input
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
 
 	for {
 		fmt.Print("🔍 Enter test string: ")
-		if !scanner.Scan() {
+		var line string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			fmt.Println("\n👋 Goodbye!")
+			return nil
+		case line, ok = <-lines:
+		}
+		if !ok {
 			break
 		}
 
-		input := strings.TrimSpace(scanner.Text())
+		input := strings.TrimSpace(line)
 		if input == "" {
 			continue
 		}
@@ -283,6 +302,11 @@ func testStringAgainstPatterns(testStr string) error {
 			matches++
 			fmt.Printf("✅ %s (Priority: %d)\n", pattern.Name, pattern.Priority)
 			fmt.Printf("   Extracted: \"%s\"\n", result[1])
+			if parsed, err := versions.Parse(result[1]); err == nil {
+				fmt.Printf("   Scheme: %s (normalized: %s)\n", parsed.Scheme, parsed.Normalized)
+			} else {
+				fmt.Printf("   Scheme: not a valid version, ignored during scans\n")
+			}
 			if verbose {
 				fmt.Printf("   Pattern: %s\n", pattern.Pattern.String())
 				fmt.Printf("   Purpose: %s\n", pattern.Purpose)
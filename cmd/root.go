@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
+
+	"binary-version-analyzer/internal"
+	"binary-version-analyzer/providers"
 )
 
 var (
@@ -15,8 +21,11 @@ var (
 	aiMaxTokens   int
 	aiBaseURL     string
 	aiTimeout     int
+	aiRetries     int
 	verbose       bool
 	configFile    string
+	profileName   string
+	offline       bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -27,14 +36,18 @@ var rootCmd = &cobra.Command{
 to extract and identify their versions using regex pattern matching combined 
 with AI-powered analysis.
 
-The tool supports multiple AI providers (Groq, OpenAI) and uses 15 different 
-regex patterns to detect version strings in various formats.`,
+The tool supports multiple AI providers (Groq, OpenAI, Ollama) and uses 15 different 
+regex patterns to detect version strings in various formats. An offline 
+heuristic ranker is available for air-gapped hosts.`,
 	Example: `  # Analyze a binary file
   binary-version-analyzer analyze /usr/bin/ls
 
   # Use OpenAI with custom settings
   binary-version-analyzer analyze /usr/bin/curl --provider openai --model gpt-4
 
+  # Rank candidates offline without any network access
+  binary-version-analyzer analyze /usr/bin/curl --offline
+
   # Show all available patterns
   binary-version-analyzer patterns list
 
@@ -44,8 +57,17 @@ regex patterns to detect version strings in various formats.`,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// Ctrl-C or SIGTERM cancels the command's context, which stops running scans
+// and AI requests; a second Ctrl-C exits immediately.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
 	if err != nil {
 		os.Exit(1)
 	}
@@ -55,42 +77,101 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Global flags
-	rootCmd.PersistentFlags().StringVar(&aiProvider, "provider", "", "AI provider to use (groq, openai)")
+	rootCmd.PersistentFlags().StringVar(&aiProvider, "provider", "", "AI provider to use (groq, openai, azure, anthropic, openai-compatible, ollama, heuristic), or a comma-separated fallback chain")
 	rootCmd.PersistentFlags().StringVar(&aiModel, "model", "", "AI model to use (overrides provider default)")
 	rootCmd.PersistentFlags().Float64Var(&aiTemperature, "temperature", -1, "AI temperature (0.0-2.0)")
-	rootCmd.PersistentFlags().IntVar(&aiMaxTokens, "max-tokens", -1, "Maximum AI response tokens (1-4096)")
+	rootCmd.PersistentFlags().IntVar(&aiMaxTokens, "max-tokens", -1, "Maximum AI response tokens (1-4096, no ceiling for openai-compatible)")
 	rootCmd.PersistentFlags().StringVar(&aiBaseURL, "base-url", "", "Custom AI API base URL")
 	rootCmd.PersistentFlags().IntVar(&aiTimeout, "timeout", -1, "Request timeout in seconds (1-300)")
+	rootCmd.PersistentFlags().IntVar(&aiRetries, "validation-retries", -1, "Re-prompts after an answer that is not a candidate (0-5)")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Use the offline heuristic ranker and never make network calls")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file (default is $HOME/.binary-version-analyzer.yaml)")
-
-	// Mark some flags as mutually exclusive or required by specific commands
-	rootCmd.MarkFlagsMutuallyExclusive("config", "provider")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Named profile from the config file")
 }
 
-// initConfig reads in config file and ENV variables if set.
+// initConfig runs before every command
 func initConfig() {
 	if verbose {
 		fmt.Println("🔧 Initializing configuration...")
 	}
+}
 
-	// Override environment variables with command line flags if provided
+// flagSettings returns the AI settings given on the command line, keyed by
+// setting name. They take precedence over every other source.
+func flagSettings() map[string]string {
+	flags := make(map[string]string)
 	if aiProvider != "" {
-		os.Setenv("AI_PROVIDER", aiProvider)
+		flags["provider"] = aiProvider
 	}
 	if aiModel != "" {
-		os.Setenv("AI_MODEL", aiModel)
+		flags["model"] = aiModel
 	}
 	if aiTemperature >= 0 {
-		os.Setenv("AI_TEMPERATURE", fmt.Sprintf("%.2f", aiTemperature))
+		flags["temperature"] = fmt.Sprintf("%.2f", aiTemperature)
 	}
 	if aiMaxTokens > 0 {
-		os.Setenv("AI_MAX_TOKENS", fmt.Sprintf("%d", aiMaxTokens))
+		flags["max_tokens"] = fmt.Sprintf("%d", aiMaxTokens)
 	}
 	if aiBaseURL != "" {
-		os.Setenv("AI_BASE_URL", aiBaseURL)
+		flags["base_url"] = aiBaseURL
 	}
 	if aiTimeout > 0 {
-		os.Setenv("AI_TIMEOUT", fmt.Sprintf("%d", aiTimeout))
+		flags["timeout"] = fmt.Sprintf("%d", aiTimeout)
+	}
+	if aiRetries >= 0 {
+		flags["validation_retries"] = fmt.Sprintf("%d", aiRetries)
+	}
+	if offline {
+		flags["offline"] = "true"
+	}
+	return flags
+}
+
+// loadFileConfig reads the config file given with --config, or the default
+// one if it exists. Without any config file an empty configuration is returned.
+func loadFileConfig() (*internal.FileConfig, error) {
+	path := configFile
+	if path == "" {
+		defaultPath, err := internal.DefaultConfigPath()
+		if err != nil {
+			return &internal.FileConfig{}, nil
+		}
+		if _, err := os.Stat(defaultPath); os.IsNotExist(err) {
+			return &internal.FileConfig{}, nil
+		}
+		path = defaultPath
+	}
+
+	if verbose {
+		fmt.Printf("📄 Using config file: %s\n", path)
+	}
+	return internal.LoadFileConfig(path)
+}
+
+// newConfigResolver creates the resolver for the AI configuration, with the
+// precedence flag > provider env > generic env > profile > defaults
+func newConfigResolver(fileConfig *internal.FileConfig) (*providers.ConfigResolver, error) {
+	name := profileName
+	if name == "" {
+		name = os.Getenv("AI_PROFILE")
+	}
+	if name == "" {
+		name = fileConfig.Profile
+	}
+
+	profile, err := fileConfig.SelectProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return providers.NewConfigResolver(flagSettings(), name, profile), nil
+}
+
+// loadAIConfig resolves the AI configuration from flags, environment and config file
+func loadAIConfig(fileConfig *internal.FileConfig) (*providers.AIConfig, error) {
+	resolver, err := newConfigResolver(fileConfig)
+	if err != nil {
+		return nil, err
 	}
+	return providers.LoadConfig(resolver)
 }
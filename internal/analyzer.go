@@ -1,11 +1,14 @@
 package internal
 
 import (
-	"bufio"
+	"context"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 
@@ -13,300 +16,270 @@ import (
 
 	"binary-version-analyzer/patterns"
 	"binary-version-analyzer/providers"
+	"binary-version-analyzer/versions"
+)
+
+// Version sources recorded in AnalysisResult
+const (
+	SourceAI          = "ai"
+	SourceGoBuildInfo = "go-buildinfo"
+	SourceRustAudit   = "cargo-auditable"
+	SourceCandidates  = "candidates" // No verdict, e.g. once the cost budget is used up
 )
 
 // BinaryAnalyzer handles binary file analysis
 type BinaryAnalyzer struct {
-	aiProvider providers.AIProvider
-	patterns   []*regexp.Regexp
+	aiProvider      providers.AIProvider
+	patterns        []patterns.VersionPattern
+	minStringLength int
+	maxCandidates   int
 }
 
 // AnalysisResult represents the result of a binary analysis
 type AnalysisResult struct {
-	BinaryPath   string    `json:"binary_path" yaml:"binary_path"`
-	BinaryName   string    `json:"binary_name" yaml:"binary_name"`
-	Version      string    `json:"version" yaml:"version"`
-	Candidates   []string  `json:"candidates" yaml:"candidates"`
-	Provider     string    `json:"ai_provider" yaml:"ai_provider"`
-	Model        string    `json:"ai_model" yaml:"ai_model"`
-	PatternCount int       `json:"pattern_count" yaml:"pattern_count"`
-	Timestamp    time.Time `json:"timestamp" yaml:"timestamp"`
+	BinaryPath    string           `json:"binary_path" yaml:"binary_path"`
+	BinaryName    string           `json:"binary_name" yaml:"binary_name"`
+	Version       string           `json:"version" yaml:"version"`
+	VersionSource string           `json:"version_source" yaml:"version_source"`
+	Scheme        versions.Scheme  `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	Candidates    []Candidate      `json:"candidates" yaml:"candidates"`
+	GoBuildInfo   *GoBuildInfo     `json:"go_build_info,omitempty" yaml:"go_build_info,omitempty"`
+	PEVersionInfo *PEVersionInfo   `json:"pe_version_info,omitempty" yaml:"pe_version_info,omitempty"`
+	DotNet        *DotNetAssembly  `json:"dotnet_assembly,omitempty" yaml:"dotnet_assembly,omitempty"`
+	MachO         *MachOSlice      `json:"macho_slice,omitempty" yaml:"macho_slice,omitempty"` // One result per slice of a universal binary
+	Rust          *RustInfo        `json:"rust,omitempty" yaml:"rust,omitempty"`
+	Dependencies  *ELFDependencies `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	Confidence    float64          `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+	Product       string           `json:"product,omitempty" yaml:"product,omitempty"`
+	Rationale     string           `json:"rationale,omitempty" yaml:"rationale,omitempty"`
+	Provider      string           `json:"ai_provider" yaml:"ai_provider"`
+	Model         string           `json:"ai_model" yaml:"ai_model"`
+	PatternCount  int              `json:"pattern_count" yaml:"pattern_count"`
+	Timestamp     time.Time        `json:"timestamp" yaml:"timestamp"`
+
+	// Providers of a fallback chain that failed before Provider answered
+	SkippedProviders []providers.SkippedProvider `json:"skipped_providers,omitempty" yaml:"skipped_providers,omitempty"`
+
+	SHA256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"` // Set when the verdict cache is used
+	Cached bool   `json:"cached,omitempty" yaml:"cached,omitempty"` // The AI verdict came from the cache
+
+	// Tokens, latency and estimated cost of the AI calls made for this binary
+	Usage *providers.Usage `json:"usage,omitempty" yaml:"usage,omitempty"`
 }
 
 // NewBinaryAnalyzer creates a new binary analyzer
 func NewBinaryAnalyzer(aiProvider providers.AIProvider) *BinaryAnalyzer {
 	return &BinaryAnalyzer{
-		aiProvider: aiProvider,
-		patterns:   patterns.GetCompiledPatterns(),
+		aiProvider:      aiProvider,
+		patterns:        patterns.GetPatternsByPriority(),
+		minStringLength: DefaultMinStringLength,
+		maxCandidates:   DefaultMaxCandidates,
 	}
 }
 
-// GetPatternCount returns the number of patterns being used
-func (ba *BinaryAnalyzer) GetPatternCount() int {
-	return len(ba.patterns)
+// SetMinStringLength sets the minimum length of printable runs that are scanned
+func (ba *BinaryAnalyzer) SetMinStringLength(length int) {
+	ba.minStringLength = length
 }
 
-// ScanBinary scans a binary file for version candidates
-func (ba *BinaryAnalyzer) ScanBinary(path string) ([]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("error opening file %s: %v", path, err)
-	}
-	defer file.Close()
-
-	var candidates []string
-	candidateSet := make(map[string]bool) // To avoid duplicates
-
-	// Use a much larger buffer for binary files and implement custom split function
-	const maxBufferSize = 4 * 1024 * 1024 // 4MB buffer
-	scanner := bufio.NewScanner(file)
-
-	// Create a custom buffer with maximum size
-	buf := make([]byte, maxBufferSize)
-	scanner.Buffer(buf, maxBufferSize)
-
-	// Use a custom split function that handles extremely long lines gracefully
-	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-		if atEOF && len(data) == 0 {
-			return 0, nil, nil
-		}
-
-		// Look for newline
-		if i := strings.IndexByte(string(data), '\n'); i >= 0 {
-			// If line is too long, truncate it
-			if i > 2000 {
-				i = 2000
-			}
-			return i + 1, data[0:i], nil
-		}
+// SetMaxCandidates sets the number of candidates after which scanning stops
+func (ba *BinaryAnalyzer) SetMaxCandidates(limit int) {
+	ba.maxCandidates = limit
+}
 
-		// If we're at EOF, return whatever we have (up to reasonable limit)
-		if atEOF {
-			if len(data) > 2000 {
-				return len(data), data[0:2000], nil
-			}
-			return len(data), data, nil
-		}
+// SelectPatterns restricts scanning to the named patterns. An empty include
+// list keeps every pattern; names in exclude are dropped afterwards.
+func (ba *BinaryAnalyzer) SelectPatterns(include, exclude []string) error {
+	known := make(map[string]bool)
+	for _, pattern := range ba.patterns {
+		known[strings.ToLower(pattern.Name)] = true
+	}
 
-		// If buffer is getting too full, process what we have
-		if len(data) > maxBufferSize-1000 {
-			// Find a reasonable break point (space, tab, or just truncate)
-			breakPoint := 2000
-			for i := 1999; i >= 1000; i-- {
-				if data[i] == ' ' || data[i] == '\t' {
-					breakPoint = i
-					break
-				}
+	toSet := func(names []string) (map[string]bool, error) {
+		set := make(map[string]bool)
+		for _, name := range names {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if !known[key] {
+				return nil, fmt.Errorf("unknown pattern: %s", name)
 			}
-			return breakPoint, data[0:breakPoint], nil
+			set[key] = true
 		}
+		return set, nil
+	}
 
-		// Request more data
-		return 0, nil, nil
-	})
-
-	lineCount := 0
-	maxLines := 50000 // Limit scanning to prevent excessive processing
-
-	for scanner.Scan() && lineCount < maxLines {
-		lineCount++
-		line := scanner.Text()
+	included, err := toSet(include)
+	if err != nil {
+		return err
+	}
+	excluded, err := toSet(exclude)
+	if err != nil {
+		return err
+	}
 
-		// Skip very long lines (likely binary data)
-		if len(line) > 1000 {
-			continue
+	var selected []patterns.VersionPattern
+	for _, pattern := range ba.patterns {
+		key := strings.ToLower(pattern.Name)
+		if (len(included) == 0 || included[key]) && !excluded[key] {
+			selected = append(selected, pattern)
 		}
+	}
 
-		// Skip binary data lines (lines with non-printable characters)
-		if !isPrintable(line) {
-			continue
-		}
+	if len(selected) == 0 {
+		return fmt.Errorf("pattern selection leaves no patterns to scan with")
+	}
+	ba.patterns = selected
+	return nil
+}
 
-		// Skip empty lines
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
+// GetPatternCount returns the number of patterns being used
+func (ba *BinaryAnalyzer) GetPatternCount() int {
+	return len(ba.patterns)
+}
 
-		for _, pattern := range ba.patterns {
-			matches := pattern.FindAllStringSubmatch(line, -1)
-			for _, match := range matches {
-				if len(match) > 1 {
-					version := strings.TrimSpace(match[1])
-					if isValidVersion(version) && !candidateSet[version] {
-						candidates = append(candidates, version)
-						candidateSet[version] = true
-					}
-				}
-			}
-		}
+// ScanBinary scans a binary file for version candidates. ELF and Mach-O files
+// are scanned section by section, other files fall back to a raw scan of the
+// whole file. Every slice of a universal binary is scanned.
+func (ba *BinaryAnalyzer) ScanBinary(path string) ([]Candidate, error) {
+	return ba.ScanBinaryContext(context.Background(), path)
+}
 
-		// Early exit if we found enough candidates
-		if len(candidates) >= 20 {
-			break
+// ScanBinaryContext is ScanBinary that stops with ctx's error once ctx is done
+func (ba *BinaryAnalyzer) ScanBinaryContext(ctx context.Context, path string) ([]Candidate, error) {
+	if elfFile, err := elf.Open(path); err == nil {
+		defer elfFile.Close()
+		if candidates, ok, err := ba.scanELF(ctx, elfFile); ok {
+			return candidates, err
 		}
 	}
-
-	// Handle scanner errors more gracefully
-	if err := scanner.Err(); err != nil {
-		// If it's still a "token too long" error, try a different approach
-		if strings.Contains(err.Error(), "token too long") {
-			return ba.scanBinaryChunked(path)
-		}
-		return nil, fmt.Errorf("error scanning file: %v", err)
+	if slices, err := ba.ReadMachO(path); err == nil {
+		return ba.scanMachOSlices(ctx, path, slices)
 	}
 
-	return candidates, nil
+	return ba.scanRaw(ctx, path)
 }
 
-// scanBinaryChunked is a fallback method for extremely problematic binary files
-func (ba *BinaryAnalyzer) scanBinaryChunked(path string) ([]string, error) {
+// scanRaw scans the whole file as a flat byte stream
+func (ba *BinaryAnalyzer) scanRaw(ctx context.Context, path string) ([]Candidate, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("error opening file %s: %v", path, err)
 	}
 	defer file.Close()
 
-	var candidates []string
-	candidateSet := make(map[string]bool)
+	collector := newCandidateCollector(ba.maxCandidates)
+	if err := ba.scanStrings(ctx, file, 0, "", collector); err != nil {
+		return nil, fmt.Errorf("error scanning file: %v", err)
+	}
 
-	// Read file in chunks and process byte by byte
-	const chunkSize = 64 * 1024 // 64KB chunks
-	buffer := make([]byte, chunkSize)
-	var lineBuffer strings.Builder
-	processedBytes := 0
-	maxBytes := 100 * 1024 * 1024 // Process max 100MB
+	return collector.candidates, nil
+}
 
-	for processedBytes < maxBytes {
-		n, err := file.Read(buffer)
-		if n == 0 {
-			break
-		}
-		processedBytes += n
-
-		for i := 0; i < n; i++ {
-			b := buffer[i]
-
-			// If we hit a newline or the line gets too long, process it
-			if b == '\n' || lineBuffer.Len() > 1000 {
-				line := lineBuffer.String()
-				lineBuffer.Reset()
-
-				// Process the line if it looks printable
-				if len(line) > 0 && len(line) <= 1000 && isPrintable(line) {
-					for _, pattern := range ba.patterns {
-						matches := pattern.FindAllStringSubmatch(line, -1)
-						for _, match := range matches {
-							if len(match) > 1 {
-								version := strings.TrimSpace(match[1])
-								if isValidVersion(version) && !candidateSet[version] {
-									candidates = append(candidates, version)
-									candidateSet[version] = true
-								}
-							}
-						}
-					}
-				}
-
-				// Early exit if we found enough candidates
-				if len(candidates) >= 20 {
-					return candidates, nil
-				}
-			} else if b >= 32 && b <= 126 {
-				// Only add printable ASCII characters
-				lineBuffer.WriteByte(b)
-			}
-		}
+// scanStrings extracts printable strings from a byte stream and matches them
+// against the patterns. baseOffset is the file offset of the stream's first byte.
+func (ba *BinaryAnalyzer) scanStrings(ctx context.Context, r io.Reader, baseOffset int64, section string, collector *candidateCollector) error {
+	return ExtractStrings(&contextReader{ctx: ctx, r: r}, baseOffset, ba.minStringLength, func(run StringRun) bool {
+		ba.matchString(run, section, collector)
 
-		if err != nil {
-			break
-		}
+		// Stop early if we found enough candidates
+		return !collector.full()
+	})
+}
+
+// contextReader fails reads once its context is done, so long scans can be cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// matchString runs every pattern against a string run and collects the versions it finds
+func (ba *BinaryAnalyzer) matchString(run StringRun, section string, collector *candidateCollector) {
+	// Every pattern captures digits, so strings without any can be skipped cheaply
+	if !strings.ContainsAny(run.Text, "0123456789") {
+		return
 	}
 
-	// Process any remaining line
-	if lineBuffer.Len() > 0 {
-		line := lineBuffer.String()
-		if isPrintable(line) {
-			for _, pattern := range ba.patterns {
-				matches := pattern.FindAllStringSubmatch(line, -1)
-				for _, match := range matches {
-					if len(match) > 1 {
-						version := strings.TrimSpace(match[1])
-						if isValidVersion(version) && !candidateSet[version] {
-							candidates = append(candidates, version)
-							candidateSet[version] = true
-						}
-					}
-				}
+	// Patterns are sorted by priority, so the first pattern to claim a
+	// version in this string is the most specific one
+	claimed := make(map[int]bool)
+	for _, pattern := range ba.patterns {
+		matches := pattern.Pattern.FindAllStringSubmatchIndex(run.Text, -1)
+		for _, match := range matches {
+			if len(match) < 4 || match[2] < 0 || claimed[match[2]] {
+				continue
 			}
+			claimed[match[2]] = true
+
+			collector.add(run.Text[match[2]:match[3]], pattern, run, match[2], match[3], section)
 		}
 	}
-
-	return candidates, nil
 }
 
 // AnalyzeWithAI uses AI to determine the most likely version from candidates
 func (ba *BinaryAnalyzer) AnalyzeWithAI(binaryName string, candidates []string) (string, error) {
-	return ba.aiProvider.AnalyzeVersions(binaryName, candidates)
+	return ba.AnalyzeWithAIContext(context.Background(), binaryName, candidates)
 }
 
-// Helper functions
-func isPrintable(s string) bool {
-	// Quick check for empty strings
-	if len(s) == 0 {
-		return false
-	}
+// AnalyzeWithAIContext is AnalyzeWithAI that cancels the AI request once ctx is done
+func (ba *BinaryAnalyzer) AnalyzeWithAIContext(ctx context.Context, binaryName string, candidates []string) (string, error) {
+	return ba.aiProvider.AnalyzeVersionsContext(ctx, binaryName, candidates)
+}
 
-	// For performance, only check first 200 characters for very long strings
-	checkLen := len(s)
-	if checkLen > 200 {
-		checkLen = 200
-	}
+// AnalyzeCandidates asks the AI provider for the most likely version, passing
+// the scan evidence along for providers that rank on it
+func (ba *BinaryAnalyzer) AnalyzeCandidates(binaryName string, candidates []Candidate) (*providers.AIResponse, error) {
+	return ba.AnalyzeCandidatesContext(context.Background(), binaryName, candidates)
+}
 
-	nonPrintableCount := 0
-	for i, r := range s {
-		if i >= checkLen {
-			break
-		}
+// AnalyzeCandidatesContext is AnalyzeCandidates that cancels the AI request once ctx is done
+func (ba *BinaryAnalyzer) AnalyzeCandidatesContext(ctx context.Context, binaryName string, candidates []Candidate) (*providers.AIResponse, error) {
+	return ba.AnalyzeRequestContext(ctx, NewRequest(binaryName, candidates))
+}
 
-		// Allow common whitespace characters
-		if r == '\t' || r == '\n' || r == '\r' {
-			continue
-		}
+// AnalyzeRequestContext sends a prepared request to the AI provider, for
+// callers that fill in more than NewRequest does, such as BinarySHA256
+func (ba *BinaryAnalyzer) AnalyzeRequestContext(ctx context.Context, req *providers.AIRequest) (*providers.AIResponse, error) {
+	return ba.aiProvider.AnalyzeRequestContext(ctx, req)
+}
 
-		// Count non-printable characters
-		if r < 32 || r > 126 {
-			nonPrintableCount++
-			// If more than 10% of checked characters are non-printable, consider it binary
-			if nonPrintableCount > checkLen/10 {
-				return false
-			}
-		}
+// NewRequest creates the AI request for a binary's candidates, carrying the
+// scan evidence along for providers that rank on it
+func NewRequest(binaryName string, candidates []Candidate) *providers.AIRequest {
+	req := &providers.AIRequest{
+		BinaryName: binaryName,
+		Candidates: Versions(candidates),
 	}
-
-	return true
+	for _, candidate := range candidates {
+		req.Evidence = append(req.Evidence, providers.CandidateEvidence{
+			Version:  candidate.Version,
+			Pattern:  candidate.Pattern,
+			Priority: candidate.Priority,
+			Count:    candidate.Count,
+			Context:  candidate.Context,
+		})
+	}
+	return req
 }
 
-func isValidVersion(version string) bool {
-	// Basic validation for version strings
-	if len(version) == 0 || len(version) > 20 {
-		return false
-	}
-
-	// Should contain at least one digit and one dot
-	hasDigit := false
-	hasDot := false
-	for _, r := range version {
-		if r >= '0' && r <= '9' {
-			hasDigit = true
-		} else if r == '.' {
-			hasDot = true
-		} else if r != '-' && r != '_' {
-			// Allow only digits, dots, hyphens, and underscores
-			return false
-		}
+// FileSHA256 returns the hex SHA-256 of a file, stopping once ctx is done
+func FileSHA256(ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening file %s: %v", path, err)
 	}
+	defer file.Close()
 
-	return hasDigit && hasDot
+	hash := sha256.New()
+	if _, err := io.Copy(hash, &contextReader{ctx: ctx, r: file}); err != nil {
+		return "", fmt.Errorf("error hashing file %s: %v", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 // SaveAsJSON saves the analysis result as JSON
@@ -349,27 +322,181 @@ func (ar *AnalysisResult) SaveAsYAML(filename string) error {
 func (ar *AnalysisResult) SaveAsText(filename string) error {
 	ar.Timestamp = time.Now()
 
+	err := os.WriteFile(filename, []byte(ar.textReport()), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing text file: %v", err)
+	}
+
+	fmt.Printf("💾 Results saved to %s\n", filename)
+	return nil
+}
+
+// textReport formats the analysis result as a plain text report
+func (ar *AnalysisResult) textReport() string {
 	var sb strings.Builder
 	sb.WriteString("Binary Version Analysis Report\n")
 	sb.WriteString("==============================\n\n")
 	sb.WriteString(fmt.Sprintf("Binary Path: %s\n", ar.BinaryPath))
 	sb.WriteString(fmt.Sprintf("Binary Name: %s\n", ar.BinaryName))
 	sb.WriteString(fmt.Sprintf("Detected Version: %s\n", ar.Version))
+	sb.WriteString(fmt.Sprintf("Version Source: %s\n", ar.VersionSource))
+	if ar.Scheme != "" {
+		sb.WriteString(fmt.Sprintf("Version Scheme: %s\n", ar.Scheme))
+	}
+	if ar.Confidence > 0 {
+		sb.WriteString(fmt.Sprintf("Confidence: %.0f%%\n", ar.Confidence*100))
+	}
+	if ar.Product != "" {
+		sb.WriteString(fmt.Sprintf("Product: %s\n", ar.Product))
+	}
+	if ar.Rationale != "" {
+		sb.WriteString(fmt.Sprintf("Rationale: %s\n", ar.Rationale))
+	}
 	sb.WriteString(fmt.Sprintf("AI Provider: %s\n", ar.Provider))
 	sb.WriteString(fmt.Sprintf("AI Model: %s\n", ar.Model))
+	for _, skipped := range ar.SkippedProviders {
+		sb.WriteString(fmt.Sprintf("Skipped Provider: %s (%s)\n", skipped.Provider, skipped.Reason))
+	}
+	if ar.Cached {
+		sb.WriteString("AI Verdict: cached\n")
+	}
+	if ar.SHA256 != "" {
+		sb.WriteString(fmt.Sprintf("SHA-256: %s\n", ar.SHA256))
+	}
+	if ar.Usage != nil {
+		sb.WriteString(fmt.Sprintf("AI Usage: %s\n", ar.Usage))
+	}
 	sb.WriteString(fmt.Sprintf("Patterns Used: %d\n", ar.PatternCount))
 	sb.WriteString(fmt.Sprintf("Analysis Time: %s\n\n", ar.Timestamp.Format(time.RFC3339)))
 
+	if ar.GoBuildInfo != nil {
+		sb.WriteString("Go Build Info:\n")
+		sb.WriteString(fmt.Sprintf("  Module: %s\n", ar.GoBuildInfo.Module))
+		sb.WriteString(fmt.Sprintf("  Version: %s\n", ar.GoBuildInfo.Version))
+		sb.WriteString(fmt.Sprintf("  Go Version: %s\n", ar.GoBuildInfo.GoVersion))
+		if ar.GoBuildInfo.VCSRevision != "" {
+			sb.WriteString(fmt.Sprintf("  VCS Revision: %s\n", ar.GoBuildInfo.VCSRevision))
+		}
+		if ar.GoBuildInfo.VCSTime != "" {
+			sb.WriteString(fmt.Sprintf("  VCS Time: %s\n", ar.GoBuildInfo.VCSTime))
+		}
+		sb.WriteString("  Dependencies:\n")
+		for _, dep := range ar.GoBuildInfo.Dependencies {
+			sb.WriteString(fmt.Sprintf("    - %s %s\n", dep.Path, dep.Version))
+		}
+		sb.WriteString("\n")
+	}
+
+	if ar.PEVersionInfo != nil {
+		sb.WriteString("PE Version Info:\n")
+		writePEVersionInfo(&sb, ar.PEVersionInfo)
+		sb.WriteString("\n")
+	}
+
+	if ar.DotNet != nil {
+		sb.WriteString(".NET Assembly:\n")
+		writeDotNetAssembly(&sb, ar.DotNet)
+		sb.WriteString("\n")
+	}
+
+	if ar.Dependencies != nil {
+		sb.WriteString("Dependencies:\n")
+		writeELFDependencies(&sb, ar.Dependencies)
+		sb.WriteString("\n")
+	}
+
+	if ar.Rust != nil {
+		sb.WriteString("Rust:\n")
+		writeRustInfo(&sb, ar.Rust)
+		sb.WriteString("\n")
+	}
+
+	if ar.MachO != nil {
+		sb.WriteString("Mach-O Slice:\n")
+		writeMachOSlice(&sb, ar.MachO)
+		sb.WriteString("\n")
+	}
+
 	sb.WriteString("Version Candidates Found:\n")
 	for i, candidate := range ar.Candidates {
 		sb.WriteString(fmt.Sprintf("  %d. %s\n", i+1, candidate))
+		sb.WriteString(fmt.Sprintf("     Pattern: %s (Priority: %d), Scheme: %s\n", candidate.Pattern, candidate.Priority, candidate.Scheme))
+		sb.WriteString(fmt.Sprintf("     Offset: 0x%x, Occurrences: %d\n", candidate.Offset, candidate.Count))
+		sb.WriteString(fmt.Sprintf("     Context: %q\n", candidate.Context))
 	}
 
-	err := os.WriteFile(filename, []byte(sb.String()), 0644)
-	if err != nil {
-		return fmt.Errorf("error writing text file: %v", err)
+	return sb.String()
+}
+
+// SaveResults saves the results of a run in format (text, json or yaml). A
+// single result is saved as is, several as a JSON array, a YAML list or
+// consecutive text reports.
+func SaveResults(results []*AnalysisResult, format, filename string) error {
+	if len(results) == 1 {
+		switch format {
+		case "json":
+			return results[0].SaveAsJSON(filename)
+		case "yaml":
+			return results[0].SaveAsYAML(filename)
+		case "text":
+			return results[0].SaveAsText(filename)
+		}
+	}
+
+	now := time.Now()
+	for _, result := range results {
+		result.Timestamp = now
+	}
+
+	var data []byte
+	var err error
+	switch format {
+	case "json":
+		if data, err = json.MarshalIndent(results, "", "  "); err != nil {
+			return fmt.Errorf("error marshaling to JSON: %v", err)
+		}
+	case "yaml":
+		if data, err = yaml.Marshal(results); err != nil {
+			return fmt.Errorf("error marshaling to YAML: %v", err)
+		}
+	case "text":
+		reports := make([]string, len(results))
+		for i, result := range results {
+			reports[i] = result.textReport()
+		}
+		data = []byte(strings.Join(reports, "\n"))
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("error writing %s file: %v", format, err)
 	}
 
 	fmt.Printf("💾 Results saved to %s\n", filename)
 	return nil
 }
+
+// SetResponse records the provider's answer along with its confidence, product and rationale
+func (ar *AnalysisResult) SetResponse(response *providers.AIResponse) {
+	ar.SetVersion(response.Version)
+	ar.Confidence = response.Confidence
+	ar.Product = response.Product
+	ar.Rationale = response.Rationale
+	ar.Provider = response.ProviderName
+	ar.Model = response.Model
+	ar.SkippedProviders = response.Skipped
+	ar.Cached = response.Cached
+}
+
+// SetVersion records the detected version in its normalized form along with its scheme
+func (ar *AnalysisResult) SetVersion(version string) {
+	parsed, err := versions.Parse(version)
+	if err != nil {
+		ar.Version = strings.TrimSpace(version)
+		ar.Scheme = ""
+		return
+	}
+	ar.Version = parsed.Normalized
+	ar.Scheme = parsed.Scheme
+}
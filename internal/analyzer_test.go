@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeBinary writes data to a file in a temporary directory and returns its path
+func writeBinary(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "binary")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+	return path
+}
+
+func TestScanBinaryIgnoresCopyrightYears(t *testing.T) {
+	path := writeBinary(t, []byte("\x00\x01Copyright (C) 1996 Free Software Foundation, Inc.\x00\x02"))
+
+	candidates, err := NewBinaryAnalyzer(nil).ScanBinary(path)
+	if err != nil {
+		t.Fatalf("ScanBinary: %v", err)
+	}
+	if len(candidates) != 0 {
+		t.Errorf("candidates = %v, want none from a copyright year", Versions(candidates))
+	}
+}
+
+func TestScanBinaryKeepsVersionNextToCopyright(t *testing.T) {
+	path := writeBinary(t, []byte("\x00ls (GNU coreutils) version 9.1\x00Copyright (C) 2022 Free Software Foundation, Inc.\x00"))
+
+	candidates, err := NewBinaryAnalyzer(nil).ScanBinary(path)
+	if err != nil {
+		t.Fatalf("ScanBinary: %v", err)
+	}
+	if versions := Versions(candidates); len(versions) != 1 || versions[0] != "9.1" {
+		t.Errorf("candidates = %v, want [9.1]", versions)
+	}
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"binary-version-analyzer/patterns"
+	"binary-version-analyzer/versions"
+)
+
+const (
+	// DefaultMaxCandidates stops scanning once this many candidates are found
+	DefaultMaxCandidates = 20
+	// contextRadius is the number of bytes kept on each side of a match
+	contextRadius = 40
+)
+
+// Candidate represents a version string found in a binary, together with the
+// evidence of where and why it was found
+type Candidate struct {
+	Version  string          `json:"version" yaml:"version"`
+	Scheme   versions.Scheme `json:"scheme" yaml:"scheme"`                       // Versioning scheme the version follows
+	Pattern  string          `json:"pattern" yaml:"pattern"`                     // Name of the VersionPattern that matched
+	Priority int             `json:"priority" yaml:"priority"`                   // Priority of that pattern (1=highest)
+	Offset   int64           `json:"offset" yaml:"offset"`                       // File offset of the version string
+	Section  string          `json:"section,omitempty" yaml:"section,omitempty"` // ELF section the version was found in
+	Encoding Encoding        `json:"encoding" yaml:"encoding"`                   // How the string holding the version was stored
+	Context  string          `json:"context" yaml:"context"`                     // Surrounding text of the match
+	Count    int             `json:"count" yaml:"count"`                         // Occurrences seen during the scan
+}
+
+// String formats the candidate with its section and non-ASCII encoding when known
+func (c Candidate) String() string {
+	var tags []string
+	if c.Section != "" {
+		tags = append(tags, c.Section)
+	}
+	if c.Encoding != "" && c.Encoding != EncodingASCII {
+		tags = append(tags, string(c.Encoding))
+	}
+
+	if len(tags) == 0 {
+		return c.Version
+	}
+	return fmt.Sprintf("%s (%s)", c.Version, strings.Join(tags, ", "))
+}
+
+// candidateCollector accumulates unique candidates across a scan
+type candidateCollector struct {
+	candidates []Candidate
+	index      map[string]int // Position of each version in candidates
+	limit      int            // Number of candidates after which the scan stops
+}
+
+func newCandidateCollector(limit int) *candidateCollector {
+	if limit < 1 {
+		limit = DefaultMaxCandidates
+	}
+	return &candidateCollector{index: make(map[string]int), limit: limit}
+}
+
+// add records a version matched by pattern at text[start:end] of a string run.
+// Versions are normalized, so "v1.2.3" and "1.2.3." count as the same candidate.
+// Repeated versions bump the occurrence count, and their evidence is replaced
+// when a higher priority pattern matches them.
+func (c *candidateCollector) add(raw string, pattern patterns.VersionPattern, run StringRun, start, end int, section string) {
+	parsed, err := versions.Parse(raw)
+	if err != nil {
+		return
+	}
+	version := parsed.Normalized
+
+	candidate := Candidate{
+		Version:  version,
+		Scheme:   parsed.Scheme,
+		Pattern:  pattern.Name,
+		Priority: pattern.Priority,
+		Offset:   run.Offset + byteOffset(run, start),
+		Section:  section,
+		Encoding: run.Encoding,
+		Context:  matchContext(run.Text, start, end),
+		Count:    1,
+	}
+
+	if i, exists := c.index[version]; exists {
+		existing := &c.candidates[i]
+		candidate.Count = existing.Count + 1
+		if candidate.Priority < existing.Priority {
+			*existing = candidate
+		} else {
+			existing.Count = candidate.Count
+		}
+		return
+	}
+
+	if c.full() {
+		return
+	}
+	c.index[version] = len(c.candidates)
+	c.candidates = append(c.candidates, candidate)
+}
+
+// full reports whether enough candidates have been collected
+func (c *candidateCollector) full() bool {
+	return len(c.candidates) >= c.limit
+}
+
+// byteOffset converts an index into the UTF-8 text of a run to a byte offset
+// in the original encoding
+func byteOffset(run StringRun, index int) int64 {
+	if run.Encoding == EncodingASCII || run.Encoding == "" {
+		return int64(index)
+	}
+	return int64(utf8.RuneCountInString(run.Text[:index])) * 2
+}
+
+// matchContext returns the text around text[start:end], trimmed to whole characters
+func matchContext(text string, start, end int) string {
+	from := start - contextRadius
+	if from < 0 {
+		from = 0
+	}
+	to := end + contextRadius
+	if to > len(text) {
+		to = len(text)
+	}
+
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from++
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to--
+	}
+
+	return strings.TrimSpace(text[from:to])
+}
+
+// PrependCandidates puts candidates read from a binary's metadata ahead of
+// the pattern results. Pattern results for the same version are dropped and
+// their occurrences counted on the metadata candidate.
+func PrependCandidates(extracted, scanned []Candidate) []Candidate {
+	if len(extracted) == 0 {
+		return scanned
+	}
+
+	index := make(map[string]int, len(extracted))
+	merged := make([]Candidate, 0, len(extracted)+len(scanned))
+	for _, candidate := range extracted {
+		if _, exists := index[candidate.Version]; exists {
+			continue
+		}
+		index[candidate.Version] = len(merged)
+		merged = append(merged, candidate)
+	}
+	for _, candidate := range scanned {
+		if i, exists := index[candidate.Version]; exists {
+			merged[i].Count += candidate.Count
+			continue
+		}
+		merged = append(merged, candidate)
+	}
+	return merged
+}
+
+// Versions returns the version strings of the given candidates
+func Versions(candidates []Candidate) []string {
+	versions := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		versions[i] = candidate.Version
+	}
+	return versions
+}
@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"binary-version-analyzer/providers"
+)
+
+// DefaultConfigFile is the name of the config file looked up in the home directory
+const DefaultConfigFile = ".binary-version-analyzer.yaml"
+
+// FileConfig represents the YAML config file. Settings are applied with this
+// precedence: flag > environment > profile > defaults.
+//
+//	profile: groq-fast
+//	profiles:
+//	  groq-fast:
+//	    provider: groq
+//	    model: llama-3.1-8b-instant
+//	  openai-accurate:
+//	    provider: openai
+//	    model: gpt-4o
+//	    temperature: 0
+//	scan:
+//	  min_length: 6
+//	  max_candidates: 30
+//	patterns:
+//	  exclude: ["Copyright Year Version"]
+//	cache:
+//	  ttl: 24h
+//	  max_size_mb: 50
+//	prices:
+//	  gpt-4o-mini: {input: 0.15, output: 0.60}
+type FileConfig struct {
+	Profile  string                        `yaml:"profile"` // Profile used when none is selected
+	Profiles map[string]*providers.Profile `yaml:"profiles"`
+	Scan     ScanConfig                    `yaml:"scan"`
+	Patterns PatternSelection              `yaml:"patterns"`
+	Cache    CacheConfig                   `yaml:"cache"`
+	Prices   providers.PriceTable          `yaml:"prices"` // USD per million tokens, merged over the defaults
+}
+
+// ScanConfig holds the settings for scanning binaries
+type ScanConfig struct {
+	MinLength     int `yaml:"min_length"`
+	MaxCandidates int `yaml:"max_candidates"`
+}
+
+// PatternSelection chooses which version patterns are scanned with, by name
+type PatternSelection struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// CacheConfig holds the settings of the AI verdict cache. AI_CACHE_DIR,
+// AI_CACHE_TTL and AI_NO_CACHE override the file.
+type CacheConfig struct {
+	Disabled  bool   `yaml:"disabled"`
+	Dir       string `yaml:"dir"`         // Defaults to $XDG_CACHE_HOME/binary-version-analyzer/verdicts
+	TTL       string `yaml:"ttl"`         // A duration such as 24h
+	MaxSizeMB int    `yaml:"max_size_mb"` // Oldest verdicts are dropped beyond this size
+}
+
+// DefaultConfigPath returns $HOME/.binary-version-analyzer.yaml
+func DefaultConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding home directory: %v", err)
+	}
+	return filepath.Join(home, DefaultConfigFile), nil
+}
+
+// LoadFileConfig reads a YAML config file
+func LoadFileConfig(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	config := &FileConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// SelectProfile returns the named profile, or the file's default profile when
+// name is empty. It returns nil when no profile is selected at all.
+func (fc *FileConfig) SelectProfile(name string) (*providers.Profile, error) {
+	if name == "" {
+		name = fc.Profile
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, exists := fc.Profiles[name]
+	if !exists || profile == nil {
+		return nil, fmt.Errorf("profile %q not found, available profiles: %v", name, fc.ProfileNames())
+	}
+	return profile, nil
+}
+
+// ProfileNames returns the names of all profiles in sorted order
+func (fc *FileConfig) ProfileNames() []string {
+	names := make([]string, 0, len(fc.Profiles))
+	for name := range fc.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CacheEnabled reports whether AI verdicts are cached
+func (fc *FileConfig) CacheEnabled() bool {
+	if value, ok := os.LookupEnv("AI_NO_CACHE"); ok && value != "" {
+		return !providers.IsTruthy(value)
+	}
+	return !fc.Cache.Disabled
+}
+
+// OpenCache returns the verdict cache with its directory, TTL and size cap
+// taken from the environment, the config file or the defaults
+func (fc *FileConfig) OpenCache() (*providers.VerdictCache, error) {
+	dir := os.Getenv("AI_CACHE_DIR")
+	if dir == "" {
+		dir = fc.Cache.Dir
+	}
+	if dir == "" {
+		defaultDir, err := providers.DefaultCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = defaultDir
+	}
+
+	ttl := providers.DefaultCacheTTL
+	ttlValue, source := os.Getenv("AI_CACHE_TTL"), "AI_CACHE_TTL"
+	if ttlValue == "" {
+		ttlValue, source = fc.Cache.TTL, "cache.ttl"
+	}
+	if ttlValue != "" {
+		parsed, err := time.ParseDuration(ttlValue)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid %s value, expected a duration such as 24h: %s", source, ttlValue)
+		}
+		ttl = parsed
+	}
+
+	if fc.Cache.MaxSizeMB < 0 {
+		return nil, fmt.Errorf("cache.max_size_mb must be positive, got: %d", fc.Cache.MaxSizeMB)
+	}
+	return providers.NewVerdictCache(dir, ttl, int64(fc.Cache.MaxSizeMB)<<20), nil
+}
+
+// PriceTable returns the default model prices with the config file's prices applied
+func (fc *FileConfig) PriceTable() (providers.PriceTable, error) {
+	for model, price := range fc.Prices {
+		if price.Input < 0 || price.Output < 0 {
+			return nil, fmt.Errorf("prices.%s must not be negative", model)
+		}
+	}
+	return providers.DefaultPrices.Merge(fc.Prices), nil
+}
+
+// ApplyScanSettings configures an analyzer with the scan and pattern settings
+func (fc *FileConfig) ApplyScanSettings(analyzer *BinaryAnalyzer) error {
+	if fc.Scan.MinLength > 0 {
+		analyzer.SetMinStringLength(fc.Scan.MinLength)
+	}
+	if fc.Scan.MaxCandidates > 0 {
+		analyzer.SetMaxCandidates(fc.Scan.MaxCandidates)
+	}
+	if len(fc.Patterns.Include) > 0 || len(fc.Patterns.Exclude) > 0 {
+		if err := analyzer.SelectPatterns(fc.Patterns.Include, fc.Patterns.Exclude); err != nil {
+			return fmt.Errorf("invalid pattern selection: %v", err)
+		}
+	}
+	return nil
+}
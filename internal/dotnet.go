@@ -0,0 +1,530 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	// peCLRDirectory is the data directory index of the CLI header
+	peCLRDirectory = 14
+	// metadataSignature starts the metadata root ("BSJB")
+	metadataSignature = 0x424A5342
+)
+
+// Metadata tables read by ReadDotNetAssembly, numbered as in ECMA-335 II.22
+const (
+	tableTypeRef         = 0x01
+	tableMemberRef       = 0x0A
+	tableCustomAttribute = 0x0C
+	tableAssembly        = 0x20
+	tableAssemblyRef     = 0x23
+)
+
+// DotNetAssembly holds the identity of a .NET assembly and the assemblies it references
+type DotNetAssembly struct {
+	Name                 string            `json:"name" yaml:"name"`
+	Version              string            `json:"version" yaml:"version"` // AssemblyVersion
+	FileVersion          string            `json:"file_version,omitempty" yaml:"file_version,omitempty"`
+	InformationalVersion string            `json:"informational_version,omitempty" yaml:"informational_version,omitempty"`
+	TargetFramework      string            `json:"target_framework,omitempty" yaml:"target_framework,omitempty"`
+	RuntimeVersion       string            `json:"runtime_version,omitempty" yaml:"runtime_version,omitempty"` // Metadata version, e.g. v4.0.30319
+	References           []DotNetReference `json:"references,omitempty" yaml:"references,omitempty"`
+
+	offset int64 // File offset of the metadata, for the candidates' evidence
+}
+
+// DotNetReference is an assembly referenced by a .NET assembly
+type DotNetReference struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version" yaml:"version"`
+}
+
+// Column types of the metadata tables. Values below colU16 are indexes into
+// the table with that number.
+const (
+	colU16 = 0x100 + iota
+	colU32
+	colString
+	colGUID
+	colBlob
+	colTypeDefOrRef
+	colHasConstant
+	colHasCustomAttribute
+	colHasFieldMarshal
+	colHasDeclSecurity
+	colMemberRefParent
+	colHasSemantics
+	colMethodDefOrRef
+	colMemberForwarded
+	colImplementation
+	colCustomAttributeType
+	colResolutionScope
+	colTypeOrMethodDef
+)
+
+// codedIndex describes an index that can point into one of several tables,
+// the table being chosen by the low tag bits
+type codedIndex struct {
+	tagBits uint
+	tables  []int // -1 marks unused tags
+}
+
+var codedIndexes = map[int]codedIndex{
+	colTypeDefOrRef:        {2, []int{0x02, 0x01, 0x1B}},
+	colHasConstant:         {2, []int{0x04, 0x08, 0x17}},
+	colHasCustomAttribute:  {5, []int{0x06, 0x04, 0x01, 0x02, 0x08, 0x09, 0x0A, 0x00, 0x0E, 0x17, 0x14, 0x11, 0x1A, 0x1B, 0x20, 0x23, 0x26, 0x27, 0x28, 0x2A, 0x2C, 0x2B}},
+	colHasFieldMarshal:     {1, []int{0x04, 0x08}},
+	colHasDeclSecurity:     {2, []int{0x02, 0x06, 0x20}},
+	colMemberRefParent:     {3, []int{0x02, 0x01, 0x1A, 0x06, 0x1B}},
+	colHasSemantics:        {1, []int{0x14, 0x17}},
+	colMethodDefOrRef:      {1, []int{0x06, 0x0A}},
+	colMemberForwarded:     {1, []int{0x04, 0x06}},
+	colImplementation:      {2, []int{0x26, 0x23, 0x27}},
+	colCustomAttributeType: {3, []int{-1, -1, 0x06, 0x0A, -1}},
+	colResolutionScope:     {2, []int{0x00, 0x1A, 0x23, 0x01}},
+	colTypeOrMethodDef:     {1, []int{0x02, 0x06}},
+}
+
+// tableSchemas lists the columns of the tables up to AssemblyRef. Later
+// tables are never read, so their layout is not needed.
+var tableSchemas = [tableAssemblyRef + 1][]int{
+	0x00: {colU16, colString, colGUID, colGUID, colGUID},                                   // Module
+	0x01: {colResolutionScope, colString, colString},                                       // TypeRef
+	0x02: {colU32, colString, colString, colTypeDefOrRef, 0x04, 0x06},                      // TypeDef
+	0x03: {0x04},                                                                           // FieldPtr
+	0x04: {colU16, colString, colBlob},                                                     // Field
+	0x05: {0x06},                                                                           // MethodPtr
+	0x06: {colU32, colU16, colU16, colString, colBlob, 0x08},                               // MethodDef
+	0x07: {0x08},                                                                           // ParamPtr
+	0x08: {colU16, colU16, colString},                                                      // Param
+	0x09: {0x02, colTypeDefOrRef},                                                          // InterfaceImpl
+	0x0A: {colMemberRefParent, colString, colBlob},                                         // MemberRef
+	0x0B: {colU16, colHasConstant, colBlob},                                                // Constant
+	0x0C: {colHasCustomAttribute, colCustomAttributeType, colBlob},                         // CustomAttribute
+	0x0D: {colHasFieldMarshal, colBlob},                                                    // FieldMarshal
+	0x0E: {colU16, colHasDeclSecurity, colBlob},                                            // DeclSecurity
+	0x0F: {colU16, colU32, 0x02},                                                           // ClassLayout
+	0x10: {colU32, 0x04},                                                                   // FieldLayout
+	0x11: {colBlob},                                                                        // StandAloneSig
+	0x12: {0x02, 0x14},                                                                     // EventMap
+	0x13: {0x14},                                                                           // EventPtr
+	0x14: {colU16, colString, colTypeDefOrRef},                                             // Event
+	0x15: {0x02, 0x17},                                                                     // PropertyMap
+	0x16: {0x17},                                                                           // PropertyPtr
+	0x17: {colU16, colString, colBlob},                                                     // Property
+	0x18: {colU16, 0x06, colHasSemantics},                                                  // MethodSemantics
+	0x19: {0x02, colMethodDefOrRef, colMethodDefOrRef},                                     // MethodImpl
+	0x1A: {colString},                                                                      // ModuleRef
+	0x1B: {colBlob},                                                                        // TypeSpec
+	0x1C: {colU16, colMemberForwarded, colString, 0x1A},                                    // ImplMap
+	0x1D: {colU32, 0x04},                                                                   // FieldRVA
+	0x1E: {colU32, colU32},                                                                 // EncLog
+	0x1F: {colU32},                                                                         // EncMap
+	0x20: {colU32, colU16, colU16, colU16, colU16, colU32, colBlob, colString, colString},  // Assembly
+	0x21: {colU32},                                                                         // AssemblyProcessor
+	0x22: {colU32, colU32, colU32},                                                         // AssemblyOS
+	0x23: {colU16, colU16, colU16, colU16, colU32, colBlob, colString, colString, colBlob}, // AssemblyRef
+}
+
+// clrMetadata gives access to the heaps and tables of a metadata root
+type clrMetadata struct {
+	version   string
+	strings   []byte
+	blob      []byte
+	heapSizes byte
+	rows      [64]uint32
+	tables    [tableAssemblyRef + 1][]byte // Rows of each table, back to back
+}
+
+// ReadDotNetAssembly extracts the assembly identity, version attributes and
+// references from the CLR metadata of a PE file. It returns an error for files
+// that are not .NET assemblies.
+func (ba *BinaryAnalyzer) ReadDotNetAssembly(path string) (*DotNetAssembly, error) {
+	f, err := openPE(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dir := peDataDirectory(f.File, peCLRDirectory)
+	if dir.VirtualAddress == 0 || dir.Size == 0 {
+		return nil, fmt.Errorf("no CLI header in PE file")
+	}
+
+	// IMAGE_COR20_HEADER: cb, runtime version, then the metadata directory
+	header, _, err := peData(f.File, dir.VirtualAddress, 16)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CLI header: %v", err)
+	}
+	data, offset, err := peData(f.File, binary.LittleEndian.Uint32(header[8:]), binary.LittleEndian.Uint32(header[12:]))
+	if err != nil {
+		return nil, fmt.Errorf("error reading CLR metadata: %v", err)
+	}
+
+	md, err := parseCLRMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CLR metadata: %v", err)
+	}
+	if md.rows[tableAssembly] == 0 {
+		return nil, fmt.Errorf("CLR metadata has no assembly manifest")
+	}
+
+	assembly := &DotNetAssembly{
+		RuntimeVersion: md.version,
+		offset:         offset,
+	}
+	row := md.row(tableAssembly, 1)
+	assembly.Version = fourPartVersion(row[4:]) // After HashAlgId
+	assembly.Name = md.string(md.column(tableAssembly, row, 7))
+
+	for i := uint32(1); i <= md.rows[tableAssemblyRef]; i++ {
+		ref := md.row(tableAssemblyRef, i)
+		assembly.References = append(assembly.References, DotNetReference{
+			Name:    md.string(md.column(tableAssemblyRef, ref, 6)),
+			Version: fourPartVersion(ref),
+		})
+	}
+
+	md.readAssemblyAttributes(assembly)
+	return assembly, nil
+}
+
+// readAssemblyAttributes reads the version and framework attributes applied
+// to the assembly
+func (md *clrMetadata) readAssemblyAttributes(assembly *DotNetAssembly) {
+	for i := uint32(1); i <= md.rows[tableCustomAttribute]; i++ {
+		row := md.row(tableCustomAttribute, i)
+		if table, index := md.decode(colHasCustomAttribute, md.column(tableCustomAttribute, row, 0)); table != tableAssembly || index != 1 {
+			continue
+		}
+
+		value, ok := md.attributeString(md.blobAt(md.column(tableCustomAttribute, row, 2)))
+		if !ok {
+			continue
+		}
+		switch md.attributeTypeName(md.column(tableCustomAttribute, row, 1)) {
+		case "AssemblyFileVersionAttribute":
+			assembly.FileVersion = value
+		case "AssemblyInformationalVersionAttribute":
+			assembly.InformationalVersion = value
+		case "TargetFrameworkAttribute":
+			assembly.TargetFramework = value
+		}
+	}
+}
+
+// attributeTypeName returns the type name of an attribute constructor that
+// is a MemberRef on a TypeRef, which is how framework attributes are referenced
+func (md *clrMetadata) attributeTypeName(ctor uint32) string {
+	table, index := md.decode(colCustomAttributeType, ctor)
+	if table != tableMemberRef || index == 0 || index > md.rows[tableMemberRef] {
+		return ""
+	}
+	parent := md.column(tableMemberRef, md.row(tableMemberRef, index), 0)
+	table, index = md.decode(colMemberRefParent, parent)
+	if table != tableTypeRef || index == 0 || index > md.rows[tableTypeRef] {
+		return ""
+	}
+	return md.string(md.column(tableTypeRef, md.row(tableTypeRef, index), 1))
+}
+
+// attributeString decodes the blob of an attribute whose constructor takes a
+// single string: the 0x0001 prolog followed by a length-prefixed UTF-8 string
+func (md *clrMetadata) attributeString(blob []byte) (string, bool) {
+	if len(blob) < 3 || blob[0] != 0x01 || blob[1] != 0x00 || blob[2] == 0xFF {
+		return "", false
+	}
+	length, n, ok := compressedUint(blob[2:])
+	if !ok || 2+n+int(length) > len(blob) {
+		return "", false
+	}
+	return string(blob[2+n : 2+n+int(length)]), true
+}
+
+// Candidates returns the informational, file and assembly versions as candidates
+func (assembly *DotNetAssembly) Candidates() []Candidate {
+	var candidates []Candidate
+	seen := make(map[string]bool)
+	add := func(raw, source string) {
+		parsed, ok := parseLeadingVersion(raw)
+		if !ok || seen[parsed.Normalized] {
+			return
+		}
+		seen[parsed.Normalized] = true
+		candidates = append(candidates, Candidate{
+			Version:  parsed.Normalized,
+			Scheme:   parsed.Scheme,
+			Pattern:  ".NET " + source,
+			Priority: 1,
+			Offset:   assembly.offset,
+			Section:  "CLR metadata",
+			Encoding: EncodingASCII,
+			Context:  fmt.Sprintf("%s %s: %s", assembly.Name, source, raw),
+			Count:    1,
+		})
+	}
+
+	add(assembly.InformationalVersion, "AssemblyInformationalVersion")
+	add(assembly.FileVersion, "AssemblyFileVersion")
+	add(assembly.Version, "AssemblyVersion")
+	return candidates
+}
+
+// FilterReferenceVersions drops candidates that are only versions of
+// referenced assemblies, so they do not compete with the assembly's own version
+func (assembly *DotNetAssembly) FilterReferenceVersions(candidates []Candidate) []Candidate {
+	own := make(map[string]bool)
+	for _, candidate := range assembly.Candidates() {
+		own[candidate.Version] = true
+	}
+	referenced := make(map[string]bool)
+	for _, ref := range assembly.References {
+		if !own[ref.Version] {
+			referenced[ref.Version] = true
+		}
+	}
+
+	var filtered []Candidate
+	for _, candidate := range candidates {
+		if !referenced[candidate.Version] {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered
+}
+
+// parseCLRMetadata reads the metadata root, its stream headers and the table
+// stream's layout
+func parseCLRMetadata(data []byte) (*clrMetadata, error) {
+	if len(data) < 16 || binary.LittleEndian.Uint32(data) != metadataSignature {
+		return nil, fmt.Errorf("missing metadata signature")
+	}
+	versionLength := int(binary.LittleEndian.Uint32(data[12:]))
+	if 16+versionLength+4 > len(data) {
+		return nil, fmt.Errorf("metadata root is truncated")
+	}
+	md := &clrMetadata{version: cString(data[16 : 16+versionLength])}
+
+	pos := 16 + versionLength
+	streams := int(binary.LittleEndian.Uint16(data[pos+2:]))
+	pos += 4
+
+	var tableStream []byte
+	for i := 0; i < streams; i++ {
+		if pos+8 > len(data) {
+			return nil, fmt.Errorf("stream header is truncated")
+		}
+		offset := uint64(binary.LittleEndian.Uint32(data[pos:]))
+		size := uint64(binary.LittleEndian.Uint32(data[pos+4:]))
+		nameEnd := pos + 8
+		for nameEnd < len(data) && data[nameEnd] != 0 {
+			nameEnd++
+		}
+		name := string(data[pos+8 : nameEnd])
+		pos = align4(nameEnd + 1)
+
+		if offset+size > uint64(len(data)) {
+			return nil, fmt.Errorf("stream %s is truncated", name)
+		}
+		stream := data[offset : offset+size]
+		switch name {
+		case "#~", "#-":
+			tableStream = stream
+		case "#Strings":
+			md.strings = stream
+		case "#Blob":
+			md.blob = stream
+		}
+	}
+	if tableStream == nil {
+		return nil, fmt.Errorf("no metadata table stream")
+	}
+	return md, md.readTables(tableStream)
+}
+
+// readTables reads the row counts of the table stream and locates the tables
+func (md *clrMetadata) readTables(stream []byte) error {
+	if len(stream) < 24 {
+		return fmt.Errorf("table stream is truncated")
+	}
+	md.heapSizes = stream[6]
+	valid := binary.LittleEndian.Uint64(stream[8:])
+
+	pos := 24
+	for table := 0; table < 64; table++ {
+		if valid&(1<<uint(table)) == 0 {
+			continue
+		}
+		if pos+4 > len(stream) {
+			return fmt.Errorf("table row counts are truncated")
+		}
+		md.rows[table] = binary.LittleEndian.Uint32(stream[pos:])
+		pos += 4
+	}
+	if md.heapSizes&0x40 != 0 {
+		pos += 4 // Extra data written by some compilers
+	}
+
+	for table := 0; table <= tableAssemblyRef; table++ {
+		size := uint64(md.rowSize(table)) * uint64(md.rows[table])
+		if uint64(pos)+size > uint64(len(stream)) {
+			return fmt.Errorf("table 0x%02x is truncated", table)
+		}
+		md.tables[table] = stream[pos : uint64(pos)+size]
+		pos += int(size)
+	}
+	return nil
+}
+
+// columnSize returns the width in bytes of a column type
+func (md *clrMetadata) columnSize(col int) int {
+	switch col {
+	case colU16:
+		return 2
+	case colU32:
+		return 4
+	case colString:
+		return heapIndexSize(md.heapSizes, 0x01)
+	case colGUID:
+		return heapIndexSize(md.heapSizes, 0x02)
+	case colBlob:
+		return heapIndexSize(md.heapSizes, 0x04)
+	}
+
+	if coded, ok := codedIndexes[col]; ok {
+		var maxRows uint32
+		for _, table := range coded.tables {
+			if table >= 0 && md.rows[table] > maxRows {
+				maxRows = md.rows[table]
+			}
+		}
+		if maxRows < 1<<(16-coded.tagBits) {
+			return 2
+		}
+		return 4
+	}
+
+	if md.rows[col] < 1<<16 {
+		return 2
+	}
+	return 4
+}
+
+func heapIndexSize(heapSizes, flag byte) int {
+	if heapSizes&flag != 0 {
+		return 4
+	}
+	return 2
+}
+
+func (md *clrMetadata) rowSize(table int) int {
+	size := 0
+	for _, col := range tableSchemas[table] {
+		size += md.columnSize(col)
+	}
+	return size
+}
+
+// row returns the bytes of a row, numbered from 1
+func (md *clrMetadata) row(table int, index uint32) []byte {
+	size := md.rowSize(table)
+	start := int(index-1) * size
+	return md.tables[table][start : start+size]
+}
+
+// column reads a column of a row as a number
+func (md *clrMetadata) column(table int, row []byte, col int) uint32 {
+	pos := 0
+	for _, c := range tableSchemas[table][:col] {
+		pos += md.columnSize(c)
+	}
+	if md.columnSize(tableSchemas[table][col]) == 2 {
+		return uint32(binary.LittleEndian.Uint16(row[pos:]))
+	}
+	return binary.LittleEndian.Uint32(row[pos:])
+}
+
+// fourPartVersion reads the Major, Minor, Build and Revision columns at the start of data
+func fourPartVersion(data []byte) string {
+	return fmt.Sprintf("%d.%d.%d.%d",
+		binary.LittleEndian.Uint16(data), binary.LittleEndian.Uint16(data[2:]),
+		binary.LittleEndian.Uint16(data[4:]), binary.LittleEndian.Uint16(data[6:]))
+}
+
+// decode splits a coded index into its table and row number
+func (md *clrMetadata) decode(col int, value uint32) (int, uint32) {
+	coded := codedIndexes[col]
+	tag := int(value & (1<<coded.tagBits - 1))
+	if tag >= len(coded.tables) {
+		return -1, 0
+	}
+	return coded.tables[tag], value >> coded.tagBits
+}
+
+// string reads a NUL-terminated string from the #Strings heap
+func (md *clrMetadata) string(offset uint32) string {
+	if uint64(offset) >= uint64(len(md.strings)) {
+		return ""
+	}
+	return cString(md.strings[offset:])
+}
+
+// blobAt reads a length-prefixed entry from the #Blob heap
+func (md *clrMetadata) blobAt(offset uint32) []byte {
+	if uint64(offset) >= uint64(len(md.blob)) {
+		return nil
+	}
+	length, n, ok := compressedUint(md.blob[offset:])
+	end := uint64(offset) + uint64(n) + uint64(length)
+	if !ok || end > uint64(len(md.blob)) {
+		return nil
+	}
+	return md.blob[uint64(offset)+uint64(n) : end]
+}
+
+// compressedUint decodes an ECMA-335 compressed unsigned integer and returns
+// it with its length in bytes
+func compressedUint(data []byte) (uint32, int, bool) {
+	switch {
+	case len(data) >= 1 && data[0]&0x80 == 0:
+		return uint32(data[0]), 1, true
+	case len(data) >= 2 && data[0]&0xC0 == 0x80:
+		return uint32(data[0]&0x3F)<<8 | uint32(data[1]), 2, true
+	case len(data) >= 4 && data[0]&0xE0 == 0xC0:
+		return uint32(data[0]&0x1F)<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3]), 4, true
+	}
+	return 0, 0, false
+}
+
+// cString returns data up to its first NUL
+func cString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return string(data[:i])
+	}
+	return string(data)
+}
+
+// writeDotNetAssembly writes the assembly identity and references for the text report
+func writeDotNetAssembly(sb *strings.Builder, assembly *DotNetAssembly) {
+	sb.WriteString(fmt.Sprintf("  Name: %s\n", assembly.Name))
+	sb.WriteString(fmt.Sprintf("  Assembly Version: %s\n", assembly.Version))
+	if assembly.FileVersion != "" {
+		sb.WriteString(fmt.Sprintf("  File Version: %s\n", assembly.FileVersion))
+	}
+	if assembly.InformationalVersion != "" {
+		sb.WriteString(fmt.Sprintf("  Informational Version: %s\n", assembly.InformationalVersion))
+	}
+	if assembly.TargetFramework != "" {
+		sb.WriteString(fmt.Sprintf("  Target Framework: %s\n", assembly.TargetFramework))
+	}
+	if assembly.RuntimeVersion != "" {
+		sb.WriteString(fmt.Sprintf("  Runtime Version: %s\n", assembly.RuntimeVersion))
+	}
+	sb.WriteString("  References:\n")
+	for _, ref := range assembly.References {
+		sb.WriteString(fmt.Sprintf("    - %s %s\n", ref.Name, ref.Version))
+	}
+}
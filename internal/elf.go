@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"debug/elf"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// preferredELFSections lists the sections that usually hold version strings,
+// in the order they are scanned. Entries ending in "." match by prefix.
+var preferredELFSections = []string{
+	".rodata",
+	".rodata.",
+	".comment",
+	".note.",
+	".data.rel.ro",
+	".data",
+}
+
+// scanELF scans the string-bearing sections of an ELF file. The boolean result
+// is false when the file has no usable sections and a raw scan is needed instead.
+func (ba *BinaryAnalyzer) scanELF(ctx context.Context, f *elf.File) ([]Candidate, bool, error) {
+	sections := orderELFSections(f)
+	if len(sections) == 0 {
+		return nil, false, nil
+	}
+
+	collector := newCandidateCollector(ba.maxCandidates)
+	for _, section := range sections {
+		if err := ba.scanStrings(ctx, section.Open(), int64(section.Offset), section.Name, collector); err != nil {
+			return nil, true, fmt.Errorf("error scanning section %s: %v", section.Name, err)
+		}
+
+		if collector.full() {
+			break
+		}
+	}
+
+	return collector.candidates, true, nil
+}
+
+// orderELFSections returns the sections worth scanning, preferred string
+// sections first and any other non-executable data sections after them
+func orderELFSections(f *elf.File) []*elf.Section {
+	var preferred, others []*elf.Section
+	rank := make(map[*elf.Section]int)
+
+	for _, section := range f.Sections {
+		if !isScannableELFSection(section) {
+			continue
+		}
+
+		if i := preferredELFSectionRank(section.Name); i >= 0 {
+			rank[section] = i
+			preferred = append(preferred, section)
+		} else {
+			others = append(others, section)
+		}
+	}
+
+	// A stable sort keeps file order within the same rank
+	sort.SliceStable(preferred, func(i, j int) bool {
+		return rank[preferred[i]] < rank[preferred[j]]
+	})
+
+	return append(preferred, others...)
+}
+
+// preferredELFSectionRank returns the scan rank of a section name, or -1
+func preferredELFSectionRank(name string) int {
+	for i, preferred := range preferredELFSections {
+		if strings.HasSuffix(preferred, ".") && strings.HasPrefix(name, preferred) {
+			return i
+		}
+		if name == preferred {
+			return i
+		}
+	}
+	return -1
+}
+
+// isScannableELFSection reports whether a section can contain readable strings
+func isScannableELFSection(section *elf.Section) bool {
+	if section.Size == 0 || section.Flags&elf.SHF_EXECINSTR != 0 {
+		return false
+	}
+
+	// DWARF data is large, often compressed and full of compiler noise
+	if strings.HasPrefix(section.Name, ".debug_") || strings.HasPrefix(section.Name, ".zdebug_") {
+		return false
+	}
+
+	// The compressed dependency tree is read by ReadRustInfo, its crate versions are not the binary's
+	if section.Name == rustDepSection {
+		return false
+	}
+
+	switch section.Type {
+	case elf.SHT_PROGBITS, elf.SHT_NOTE, elf.SHT_STRTAB:
+		return true
+	default:
+		return false
+	}
+}
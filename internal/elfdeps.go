@@ -0,0 +1,287 @@
+package internal
+
+import (
+	"debug/elf"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"binary-version-analyzer/versions"
+)
+
+const (
+	// verFlagBase marks the version definition that names the file itself
+	verFlagBase = 0x1
+	// maxVersionEntries guards against version chains that point back at themselves
+	maxVersionEntries = 4096
+)
+
+// ELFDependencies holds the shared libraries an ELF file needs and the
+// versioned symbol sets it requires from them or defines itself
+type ELFDependencies struct {
+	SOName   string          `json:"soname,omitempty" yaml:"soname,omitempty"` // From DT_SONAME
+	Needed   []string        `json:"needed,omitempty" yaml:"needed,omitempty"` // From DT_NEEDED
+	GLIBC    string          `json:"min_glibc,omitempty" yaml:"min_glibc,omitempty"`
+	GLIBCXX  string          `json:"min_libstdcxx,omitempty" yaml:"min_libstdcxx,omitempty"` // Newest GLIBCXX_ set, the libstdc++ equivalent
+	Required []ELFVersionSet `json:"required_versions,omitempty" yaml:"required_versions,omitempty"`
+	Defined  []string        `json:"defined_versions,omitempty" yaml:"defined_versions,omitempty"` // From .gnu.version_d
+}
+
+// ELFVersionSet is a family of versioned symbols required from one library,
+// e.g. GLIBC_2.2.5 to GLIBC_2.34 from libc.so.6
+type ELFVersionSet struct {
+	Library  string   `json:"library" yaml:"library"`
+	Name     string   `json:"name" yaml:"name"`       // Prefix such as GLIBC, GLIBCXX or CXXABI
+	Minimum  string   `json:"minimum" yaml:"minimum"` // Newest version required, so the oldest release that works
+	Versions []string `json:"versions" yaml:"versions"`
+}
+
+// ReadELFDependencies reads DT_NEEDED, DT_SONAME, .gnu.version_r and
+// .gnu.version_d. It returns an error for files that are not dynamically
+// linked ELF files.
+func (ba *BinaryAnalyzer) ReadELFDependencies(path string) (*ELFDependencies, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ELF file: %v", err)
+	}
+	defer f.Close()
+
+	deps := &ELFDependencies{}
+	if needed, err := f.DynString(elf.DT_NEEDED); err == nil {
+		deps.Needed = needed
+	}
+	if soname, err := f.DynString(elf.DT_SONAME); err == nil && len(soname) > 0 {
+		deps.SOName = soname[0]
+	}
+
+	if section := f.SectionByType(elf.SHT_GNU_VERNEED); section != nil {
+		if err := deps.readVersionNeeds(f, section); err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", section.Name, err)
+		}
+	}
+	if section := f.SectionByType(elf.SHT_GNU_VERDEF); section != nil {
+		if err := deps.readVersionDefs(f, section); err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", section.Name, err)
+		}
+	}
+
+	if len(deps.Needed) == 0 && deps.SOName == "" && len(deps.Required) == 0 && len(deps.Defined) == 0 {
+		return nil, fmt.Errorf("no dynamic dependencies")
+	}
+	return deps, nil
+}
+
+// readVersionNeeds walks the Verneed entries and their Vernaux versions
+func (deps *ELFDependencies) readVersionNeeds(f *elf.File, section *elf.Section) error {
+	data, strtab, err := versionSectionData(f, section)
+	if err != nil {
+		return err
+	}
+
+	sets := make(map[string]*ELFVersionSet)
+	var keys []string // Sets in the order they first appear
+	order := f.ByteOrder
+	offset := 0
+	for entries := 0; entries < maxVersionEntries && offset+16 <= len(data); entries++ {
+		count := int(order.Uint16(data[offset+2:]))
+		library := stringAt(strtab, order.Uint32(data[offset+4:]))
+		aux := offset + int(order.Uint32(data[offset+8:]))
+
+		for i := 0; i < count && aux+16 <= len(data); i++ {
+			name := stringAt(strtab, order.Uint32(data[aux+8:]))
+			if prefix, version, ok := splitSymbolVersion(name); ok {
+				key := library + "\x00" + prefix
+				set, exists := sets[key]
+				if !exists {
+					set = &ELFVersionSet{Library: library, Name: prefix}
+					sets[key] = set
+					keys = append(keys, key)
+				}
+				set.Versions = append(set.Versions, version)
+			}
+
+			next := int(order.Uint32(data[aux+12:]))
+			if next == 0 {
+				break
+			}
+			aux += next
+		}
+
+		next := int(order.Uint32(data[offset+12:]))
+		if next == 0 {
+			break
+		}
+		offset += next
+	}
+
+	for _, key := range keys {
+		set := sets[key]
+		sort.Slice(set.Versions, func(i, j int) bool {
+			return compareVersionNumbers(set.Versions[i], set.Versions[j]) < 0
+		})
+		set.Minimum = set.Versions[len(set.Versions)-1]
+		deps.Required = append(deps.Required, *set)
+
+		// libc and libm both carry GLIBC sets, the newest of them counts
+		switch set.Name {
+		case "GLIBC":
+			deps.GLIBC = newerVersion(deps.GLIBC, set.Minimum)
+		case "GLIBCXX":
+			deps.GLIBCXX = newerVersion(deps.GLIBCXX, set.Minimum)
+		}
+	}
+	return nil
+}
+
+// readVersionDefs walks the Verdef entries, skipping the one naming the file itself
+func (deps *ELFDependencies) readVersionDefs(f *elf.File, section *elf.Section) error {
+	data, strtab, err := versionSectionData(f, section)
+	if err != nil {
+		return err
+	}
+
+	order := f.ByteOrder
+	offset := 0
+	for entries := 0; entries < maxVersionEntries && offset+20 <= len(data); entries++ {
+		flags := order.Uint16(data[offset+2:])
+		aux := offset + int(order.Uint32(data[offset+12:]))
+		if flags&verFlagBase == 0 && aux+8 <= len(data) {
+			deps.Defined = append(deps.Defined, stringAt(strtab, order.Uint32(data[aux:])))
+		}
+
+		next := int(order.Uint32(data[offset+16:]))
+		if next == 0 {
+			break
+		}
+		offset += next
+	}
+	return nil
+}
+
+// versionSectionData returns a version section and the string table it links to
+func versionSectionData(f *elf.File, section *elf.Section) ([]byte, []byte, error) {
+	data, err := section.Data()
+	if err != nil {
+		return nil, nil, err
+	}
+	if int(section.Link) >= len(f.Sections) {
+		return nil, nil, fmt.Errorf("invalid string table index %d", section.Link)
+	}
+	strtab, err := f.Sections[section.Link].Data()
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, strtab, nil
+}
+
+// stringAt returns the NUL-terminated string at offset in a string table
+func stringAt(strtab []byte, offset uint32) string {
+	if int(offset) >= len(strtab) {
+		return ""
+	}
+	return cString(strtab[offset:])
+}
+
+// splitSymbolVersion splits a symbol version such as GLIBC_2.2.5 into its
+// prefix and number. Names without a number, such as GLIBC_PRIVATE, are rejected.
+func splitSymbolVersion(name string) (string, string, bool) {
+	i := strings.LastIndex(name, "_")
+	if i <= 0 || i == len(name)-1 {
+		return "", "", false
+	}
+	prefix, version := name[:i], name[i+1:]
+	for _, part := range strings.Split(version, ".") {
+		if _, err := strconv.Atoi(part); err != nil {
+			return "", "", false
+		}
+	}
+	return prefix, version, true
+}
+
+// compareVersionNumbers compares dotted numeric versions component by component
+func compareVersionNumbers(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// newerVersion returns the newer of two dotted versions, ignoring an empty one
+func newerVersion(a, b string) string {
+	if a == "" || compareVersionNumbers(b, a) > 0 {
+		return b
+	}
+	return a
+}
+
+// FilterDependencyVersions drops candidates whose only evidence is a required
+// symbol version such as GLIBC_2.34, so the versions of linked libraries do
+// not compete with the binary's own version. Candidates also seen elsewhere
+// keep that evidence and stay.
+func (deps *ELFDependencies) FilterDependencyVersions(candidates []Candidate) []Candidate {
+	required := make(map[string][]string) // Normalized version -> symbol version names
+	for _, set := range deps.Required {
+		for _, version := range set.Versions {
+			parsed, err := versions.Parse(version)
+			if err != nil {
+				continue
+			}
+			required[parsed.Normalized] = append(required[parsed.Normalized], set.Name+"_"+version)
+		}
+	}
+
+	var filtered []Candidate
+	for _, candidate := range candidates {
+		if !isRequiredSymbolVersion(candidate, required[candidate.Version]) {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered
+}
+
+// isRequiredSymbolVersion reports whether a candidate was found in one of the
+// given symbol version names
+func isRequiredSymbolVersion(candidate Candidate, names []string) bool {
+	for _, name := range names {
+		if strings.Contains(candidate.Context, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeELFDependencies writes the dependencies for the text report
+func writeELFDependencies(sb *strings.Builder, deps *ELFDependencies) {
+	if deps.SOName != "" {
+		sb.WriteString(fmt.Sprintf("  SONAME: %s\n", deps.SOName))
+	}
+	if deps.GLIBC != "" {
+		sb.WriteString(fmt.Sprintf("  Minimum glibc: %s\n", deps.GLIBC))
+	}
+	if deps.GLIBCXX != "" {
+		sb.WriteString(fmt.Sprintf("  Minimum libstdc++ (GLIBCXX): %s\n", deps.GLIBCXX))
+	}
+	for _, lib := range deps.Needed {
+		sb.WriteString(fmt.Sprintf("  Needed: %s\n", lib))
+	}
+	for _, set := range deps.Required {
+		sb.WriteString(fmt.Sprintf("  Requires: %s %s_%s (%s)\n", set.Library, set.Name, set.Minimum, strings.Join(set.Versions, ", ")))
+	}
+	if len(deps.Defined) > 0 {
+		sb.WriteString(fmt.Sprintf("  Defines: %s\n", strings.Join(deps.Defined, ", ")))
+	}
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strings"
+)
+
+// pseudoVersionPattern matches Go module pseudo-versions such as
+// v0.0.0-20170904143325-4b6fd5b0f2e4, which are never real release tags
+var pseudoVersionPattern = regexp.MustCompile(`^v[0-9]+\.(0\.0-|\d+\.\d+-([^+]*\.)?0\.)\d{14}-[A-Za-z0-9]+(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+
+// GoBuildInfo holds the build information embedded in Go binaries
+type GoBuildInfo struct {
+	GoVersion    string     `json:"go_version" yaml:"go_version"`
+	Path         string     `json:"path" yaml:"path"`
+	Module       string     `json:"module" yaml:"module"`
+	Version      string     `json:"version" yaml:"version"`
+	VCSRevision  string     `json:"vcs_revision,omitempty" yaml:"vcs_revision,omitempty"`
+	VCSTime      string     `json:"vcs_time,omitempty" yaml:"vcs_time,omitempty"`
+	Dependencies []GoModule `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+}
+
+// GoModule represents a module dependency recorded in a Go binary
+type GoModule struct {
+	Path    string `json:"path" yaml:"path"`
+	Version string `json:"version" yaml:"version"`
+	Replace string `json:"replace,omitempty" yaml:"replace,omitempty"`
+}
+
+// ReadGoBuildInfo extracts the Go build information from a binary.
+// It returns an error for files that are not Go binaries.
+func (ba *BinaryAnalyzer) ReadGoBuildInfo(path string) (*GoBuildInfo, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Go build info: %v", err)
+	}
+	return newGoBuildInfo(info), nil
+}
+
+// newGoBuildInfo converts the build information read by debug/buildinfo
+func newGoBuildInfo(info *debug.BuildInfo) *GoBuildInfo {
+	goInfo := &GoBuildInfo{
+		GoVersion: info.GoVersion,
+		Path:      info.Path,
+		Module:    info.Main.Path,
+		Version:   info.Main.Version,
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			goInfo.VCSRevision = setting.Value
+		case "vcs.time":
+			goInfo.VCSTime = setting.Value
+		}
+	}
+
+	for _, dep := range info.Deps {
+		goInfo.Dependencies = append(goInfo.Dependencies, newGoModule(dep))
+	}
+
+	return goInfo
+}
+
+func newGoModule(mod *debug.Module) GoModule {
+	module := GoModule{
+		Path:    mod.Path,
+		Version: mod.Version,
+	}
+	if mod.Replace != nil {
+		module.Replace = strings.TrimSpace(mod.Replace.Path + " " + mod.Replace.Version)
+	}
+	return module
+}
+
+// HasReleaseVersion reports whether the main module version is a real tag
+// rather than "(devel)" or a pseudo-version
+func (gi *GoBuildInfo) HasReleaseVersion() bool {
+	if gi.Version == "" || gi.Version == "(devel)" {
+		return false
+	}
+	return !pseudoVersionPattern.MatchString(gi.Version)
+}
+
+// ReleaseVersion returns the main module version without its "v" prefix
+func (gi *GoBuildInfo) ReleaseVersion() string {
+	return strings.TrimPrefix(gi.Version, "v")
+}
+
+// FilterDependencyVersions drops candidates that are only versions of
+// dependencies, so they do not compete with the binary's own version
+func (gi *GoBuildInfo) FilterDependencyVersions(candidates []Candidate) []Candidate {
+	depVersions := make(map[string]bool)
+	for _, dep := range gi.Dependencies {
+		depVersions[strings.TrimPrefix(dep.Version, "v")] = true
+
+		// Patterns stop before the commit hash of a pseudo-version
+		if pseudoVersionPattern.MatchString(dep.Version) {
+			trimmed := strings.TrimPrefix(dep.Version, "v")
+			depVersions[trimmed[:strings.LastIndex(trimmed, "-")]] = true
+		}
+	}
+	delete(depVersions, gi.ReleaseVersion())
+
+	var filtered []Candidate
+	for _, candidate := range candidates {
+		if !depVersions[candidate.Version] {
+			filtered = append(filtered, candidate)
+		}
+	}
+
+	// Keep the original list if every candidate was a dependency version
+	if len(filtered) == 0 {
+		return candidates
+	}
+	return filtered
+}
@@ -0,0 +1,509 @@
+package internal
+
+import (
+	"context"
+	"debug/buildinfo"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"binary-version-analyzer/versions"
+)
+
+// Load commands that debug/macho leaves undecoded
+const (
+	lcIDDylib            macho.LoadCmd = 0xd
+	lcLoadWeakDylib      macho.LoadCmd = 0x18 | 0x80000000
+	lcReexportDylib      macho.LoadCmd = 0x1f | 0x80000000
+	lcLazyLoadDylib      macho.LoadCmd = 0x20
+	lcLoadUpwardDylib    macho.LoadCmd = 0x23 | 0x80000000
+	lcVersionMinMacOSX   macho.LoadCmd = 0x24
+	lcVersionMinIPhoneOS macho.LoadCmd = 0x25
+	lcSourceVersion      macho.LoadCmd = 0x2a
+	lcVersionMinTvOS     macho.LoadCmd = 0x2f
+	lcVersionMinWatchOS  macho.LoadCmd = 0x30
+	lcBuildVersion       macho.LoadCmd = 0x32
+)
+
+// Section attributes and types of sections that hold no strings
+const (
+	machoSectionTypeMask         = 0xff
+	machoZerofill                = 0x1
+	machoGBZerofill              = 0xc
+	machoThreadLocalZerofill     = 0x12
+	machoAttrPureInstructions    = 0x80000000
+	machoAttrSomeInstructions    = 0x400
+	machoCPUSubtypeMask          = 0xff
+	machoCPUSubtypeARM64E        = 2
+	machoCPUSubtypeX86_64Haswell = 8
+)
+
+// machoPlatforms names the platforms of LC_BUILD_VERSION
+var machoPlatforms = map[uint32]string{
+	1:  "macOS",
+	2:  "iOS",
+	3:  "tvOS",
+	4:  "watchOS",
+	5:  "bridgeOS",
+	6:  "Mac Catalyst",
+	7:  "iOS Simulator",
+	8:  "tvOS Simulator",
+	9:  "watchOS Simulator",
+	10: "DriverKit",
+	11: "visionOS",
+	12: "visionOS Simulator",
+}
+
+// machoVersionMinPlatforms names the platforms of the LC_VERSION_MIN_* commands
+var machoVersionMinPlatforms = map[macho.LoadCmd]string{
+	lcVersionMinMacOSX:   "macOS",
+	lcVersionMinIPhoneOS: "iOS",
+	lcVersionMinTvOS:     "tvOS",
+	lcVersionMinWatchOS:  "watchOS",
+}
+
+// preferredMachOSections lists the sections that usually hold version
+// strings, in the order they are scanned
+var preferredMachOSections = []string{
+	"__TEXT,__cstring",
+	"__TEXT,__const",
+	"__DATA_CONST,__const",
+	"__DATA,__const",
+	"__DATA,__data",
+}
+
+// MachOSlice holds the version load commands of one architecture of a Mach-O
+// file. Thin files have a single slice at offset zero.
+type MachOSlice struct {
+	Arch          string       `json:"arch" yaml:"arch"`
+	Type          string       `json:"type" yaml:"type"` // executable, dylib, bundle...
+	Offset        int64        `json:"offset" yaml:"offset"`
+	Size          int64        `json:"size" yaml:"size"`
+	SourceVersion string       `json:"source_version,omitempty" yaml:"source_version,omitempty"` // From LC_SOURCE_VERSION
+	Platform      string       `json:"platform,omitempty" yaml:"platform,omitempty"`
+	MinOS         string       `json:"min_os,omitempty" yaml:"min_os,omitempty"`
+	SDK           string       `json:"sdk,omitempty" yaml:"sdk,omitempty"`
+	ID            *MachODylib  `json:"id,omitempty" yaml:"id,omitempty"` // From LC_ID_DYLIB, for libraries
+	Dylibs        []MachODylib `json:"dylibs,omitempty" yaml:"dylibs,omitempty"`
+
+	// Versions of the tools that built the slice, from LC_BUILD_VERSION
+	Tools []MachOTool `json:"tools,omitempty" yaml:"tools,omitempty"`
+}
+
+// MachODylib is a dynamic library a slice identifies as or links against
+type MachODylib struct {
+	Name                 string `json:"name" yaml:"name"`
+	CurrentVersion       string `json:"current_version" yaml:"current_version"`
+	CompatibilityVersion string `json:"compatibility_version" yaml:"compatibility_version"`
+}
+
+// MachOTool is a build tool recorded in LC_BUILD_VERSION
+type MachOTool struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version" yaml:"version"`
+}
+
+// machoTools names the tools of LC_BUILD_VERSION
+var machoTools = map[uint32]string{1: "clang", 2: "swift", 3: "ld", 4: "lld"}
+
+// ReadMachO reads the version load commands of every slice of a Mach-O file.
+// It returns an error for files that are neither thin nor universal Mach-O files.
+func (ba *BinaryAnalyzer) ReadMachO(path string) ([]*MachOSlice, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	fat, err := macho.NewFatFile(file)
+	if err == nil {
+		var slices []*MachOSlice
+		for _, arch := range fat.Arches {
+			slices = append(slices, newMachOSlice(arch.File, int64(arch.Offset), int64(arch.Size)))
+		}
+		return slices, nil
+	}
+	if !errors.Is(err, macho.ErrNotFat) {
+		return nil, fmt.Errorf("error reading universal binary: %v", err)
+	}
+
+	f, err := macho.NewFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Mach-O file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %v", path, err)
+	}
+	return []*MachOSlice{newMachOSlice(f, 0, info.Size())}, nil
+}
+
+// newMachOSlice decodes the version load commands of a Mach-O image
+func newMachOSlice(f *macho.File, offset, size int64) *MachOSlice {
+	slice := &MachOSlice{
+		Arch:   machoArch(f.Cpu, f.SubCpu),
+		Type:   machoType(f.Type),
+		Offset: offset,
+		Size:   size,
+	}
+
+	order := f.ByteOrder
+	for _, load := range f.Loads {
+		raw := load.Raw()
+		if len(raw) < 8 {
+			continue
+		}
+		cmd := macho.LoadCmd(order.Uint32(raw))
+		switch cmd {
+		case macho.LoadCmdDylib, lcLoadWeakDylib, lcReexportDylib, lcLazyLoadDylib, lcLoadUpwardDylib:
+			if dylib, ok := machoDylib(raw, order); ok {
+				slice.Dylibs = append(slice.Dylibs, dylib)
+			}
+		case lcIDDylib:
+			if dylib, ok := machoDylib(raw, order); ok {
+				slice.ID = &dylib
+			}
+		case lcSourceVersion:
+			if len(raw) >= 16 {
+				slice.SourceVersion = sourceVersion(order.Uint64(raw[8:]))
+			}
+		case lcBuildVersion:
+			if len(raw) < 24 {
+				continue
+			}
+			slice.Platform = machoPlatforms[order.Uint32(raw[8:])]
+			slice.MinOS = nibbleVersion(order.Uint32(raw[12:]))
+			slice.SDK = nibbleVersion(order.Uint32(raw[16:]))
+			ntools := int(order.Uint32(raw[20:]))
+			for i := 0; i < ntools && 24+8*i+8 <= len(raw); i++ {
+				tool := raw[24+8*i:]
+				name, ok := machoTools[order.Uint32(tool)]
+				if !ok {
+					name = fmt.Sprintf("tool %d", order.Uint32(tool))
+				}
+				slice.Tools = append(slice.Tools, MachOTool{Name: name, Version: nibbleVersion(order.Uint32(tool[4:]))})
+			}
+		case lcVersionMinMacOSX, lcVersionMinIPhoneOS, lcVersionMinTvOS, lcVersionMinWatchOS:
+			// LC_BUILD_VERSION supersedes the older commands when both are present
+			if len(raw) < 16 || slice.Platform != "" {
+				continue
+			}
+			slice.Platform = machoVersionMinPlatforms[cmd]
+			slice.MinOS = nibbleVersion(order.Uint32(raw[8:]))
+			slice.SDK = nibbleVersion(order.Uint32(raw[12:]))
+		}
+	}
+	return slice
+}
+
+// machoDylib decodes a dylib_command
+func machoDylib(raw []byte, order binary.ByteOrder) (MachODylib, bool) {
+	if len(raw) < 24 {
+		return MachODylib{}, false
+	}
+	nameOffset := order.Uint32(raw[8:])
+	if nameOffset >= uint32(len(raw)) {
+		return MachODylib{}, false
+	}
+	return MachODylib{
+		Name:                 cString(raw[nameOffset:]),
+		CurrentVersion:       packedVersion(order.Uint32(raw[16:])),
+		CompatibilityVersion: packedVersion(order.Uint32(raw[20:])),
+	}, true
+}
+
+// packedVersion formats a version packed as xxxx.yy.zz
+func packedVersion(v uint32) string {
+	return fmt.Sprintf("%d.%d.%d", v>>16, (v>>8)&0xff, v&0xff)
+}
+
+// nibbleVersion is packedVersion that returns "" for 0.0.0, which marks an
+// unset OS or tool version
+func nibbleVersion(v uint32) string {
+	if v == 0 {
+		return ""
+	}
+	return packedVersion(v)
+}
+
+// sourceVersion formats a version packed as a.b.c.d.e in 24.10.10.10.10 bits,
+// without its trailing zero components, or returns "" for 0
+func sourceVersion(v uint64) string {
+	if v == 0 {
+		return ""
+	}
+	parts := []uint64{v >> 40, (v >> 30) & 0x3ff, (v >> 20) & 0x3ff, (v >> 10) & 0x3ff, v & 0x3ff}
+	for len(parts) > 2 && parts[len(parts)-1] == 0 {
+		parts = parts[:len(parts)-1]
+	}
+	formatted := make([]string, len(parts))
+	for i, part := range parts {
+		formatted[i] = fmt.Sprint(part)
+	}
+	return strings.Join(formatted, ".")
+}
+
+// machoArch names a CPU type the way lipo does
+func machoArch(cpu macho.Cpu, subCPU uint32) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		if subCPU&machoCPUSubtypeMask == machoCPUSubtypeX86_64Haswell {
+			return "x86_64h"
+		}
+		return "x86_64"
+	case macho.CpuArm64:
+		if subCPU&machoCPUSubtypeMask == machoCPUSubtypeARM64E {
+			return "arm64e"
+		}
+		return "arm64"
+	case macho.Cpu386:
+		return "i386"
+	case macho.CpuArm:
+		return "arm"
+	case macho.CpuPpc:
+		return "ppc"
+	case macho.CpuPpc64:
+		return "ppc64"
+	default:
+		return fmt.Sprintf("cpu %d", uint32(cpu))
+	}
+}
+
+// machoType names a Mach-O file type
+func machoType(t macho.Type) string {
+	switch t {
+	case macho.TypeExec:
+		return "executable"
+	case macho.TypeDylib:
+		return "dylib"
+	case macho.TypeBundle:
+		return "bundle"
+	case macho.TypeObj:
+		return "object"
+	default:
+		return fmt.Sprintf("type %d", uint32(t))
+	}
+}
+
+// Label names the slice in output, e.g. "arm64 executable"
+func (slice *MachOSlice) Label() string {
+	return slice.Arch + " " + slice.Type
+}
+
+// open returns a reader over the slice's bytes in the file
+func (slice *MachOSlice) open(file *os.File) *io.SectionReader {
+	return io.NewSectionReader(file, slice.Offset, slice.Size)
+}
+
+// ScanMachOSlice scans the string-bearing sections of one slice of a Mach-O file
+func (ba *BinaryAnalyzer) ScanMachOSlice(ctx context.Context, path string, slice *MachOSlice) ([]Candidate, error) {
+	return ba.scanMachOSlices(ctx, path, []*MachOSlice{slice})
+}
+
+// scanMachOSlices scans every slice of a Mach-O file into one candidate list
+func (ba *BinaryAnalyzer) scanMachOSlices(ctx context.Context, path string, slices []*MachOSlice) ([]Candidate, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	collector := newCandidateCollector(ba.maxCandidates)
+	for _, slice := range slices {
+		f, err := macho.NewFile(slice.open(file))
+		if err != nil {
+			return nil, fmt.Errorf("error reading Mach-O slice %s: %v", slice.Arch, err)
+		}
+		if err := ba.scanMachO(ctx, f, slice.Offset, collector); err != nil {
+			return nil, err
+		}
+		if collector.full() {
+			break
+		}
+	}
+	return collector.candidates, nil
+}
+
+// scanMachO scans the sections of a Mach-O image that starts at baseOffset in the file
+func (ba *BinaryAnalyzer) scanMachO(ctx context.Context, f *macho.File, baseOffset int64, collector *candidateCollector) error {
+	for _, section := range orderMachOSections(f) {
+		name := section.Seg + "," + section.Name
+		if err := ba.scanStrings(ctx, section.Open(), baseOffset+int64(section.Offset), name, collector); err != nil {
+			return fmt.Errorf("error scanning section %s: %v", name, err)
+		}
+		if collector.full() {
+			break
+		}
+	}
+	return nil
+}
+
+// orderMachOSections returns the sections worth scanning, preferred string
+// sections first and any other data sections after them
+func orderMachOSections(f *macho.File) []*macho.Section {
+	var others []*macho.Section
+	preferred := make([]*macho.Section, len(preferredMachOSections))
+	for _, section := range f.Sections {
+		if !isScannableMachOSection(section) {
+			continue
+		}
+		found := false
+		for i, name := range preferredMachOSections {
+			if section.Seg+","+section.Name == name && preferred[i] == nil {
+				preferred[i] = section
+				found = true
+				break
+			}
+		}
+		if !found {
+			others = append(others, section)
+		}
+	}
+
+	var ordered []*macho.Section
+	for _, section := range preferred {
+		if section != nil {
+			ordered = append(ordered, section)
+		}
+	}
+	return append(ordered, others...)
+}
+
+// isScannableMachOSection reports whether a section can contain readable strings
+func isScannableMachOSection(section *macho.Section) bool {
+	if section.Size == 0 || section.Offset == 0 || section.Flags&(machoAttrPureInstructions|machoAttrSomeInstructions) != 0 {
+		return false
+	}
+
+	// DWARF data is large and full of compiler noise, and the cargo auditable
+	// dependency tree is compressed
+	if section.Seg == "__DWARF" || section.Name == rustDepSection {
+		return false
+	}
+
+	switch section.Flags & machoSectionTypeMask {
+	case machoZerofill, machoGBZerofill, machoThreadLocalZerofill:
+		return false
+	default:
+		return true
+	}
+}
+
+// ReadMachOGoBuildInfo extracts the Go build information of one slice, so
+// each architecture of a universal Go binary reports its own build
+func (ba *BinaryAnalyzer) ReadMachOGoBuildInfo(path string, slice *MachOSlice) (*GoBuildInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	info, err := buildinfo.Read(slice.open(file))
+	if err != nil {
+		return nil, fmt.Errorf("error reading Go build info: %v", err)
+	}
+	return newGoBuildInfo(info), nil
+}
+
+// Candidates returns the slice's own versions: its source version and, for
+// libraries, the current version of its install name
+func (slice *MachOSlice) Candidates() []Candidate {
+	var candidates []Candidate
+	seen := make(map[string]bool)
+	add := func(raw, source, context string) {
+		parsed, err := versions.Parse(raw)
+		if raw == "" || err != nil || seen[parsed.Normalized] {
+			return
+		}
+		seen[parsed.Normalized] = true
+		candidates = append(candidates, Candidate{
+			Version:  parsed.Normalized,
+			Scheme:   parsed.Scheme,
+			Pattern:  "Mach-O " + source,
+			Priority: 1,
+			Offset:   slice.Offset,
+			Section:  "load commands",
+			Encoding: EncodingASCII,
+			Context:  context,
+			Count:    1,
+		})
+	}
+
+	add(slice.SourceVersion, "LC_SOURCE_VERSION", "source version "+slice.SourceVersion)
+	if slice.ID != nil {
+		add(slice.ID.CurrentVersion, "LC_ID_DYLIB", fmt.Sprintf("%s current version %s", slice.ID.Name, slice.ID.CurrentVersion))
+	}
+	return candidates
+}
+
+// FilterDependencyVersions drops candidates found next to the name of a
+// linked library, the target OS, the SDK or a build tool whose version they
+// equal, so those versions do not compete with the slice's own. A bare value
+// that happens to equal one of them, such as libSystem's compatibility
+// version 1.0.0, stays.
+func (slice *MachOSlice) FilterDependencyVersions(candidates []Candidate) []Candidate {
+	foreign := make(map[string][]string) // Normalized version -> names that mark it as foreign
+	addForeign := func(raw string, names ...string) {
+		if parsed, err := versions.Parse(raw); raw != "" && err == nil {
+			foreign[parsed.Normalized] = append(foreign[parsed.Normalized], names...)
+		}
+	}
+	for _, dylib := range slice.Dylibs {
+		addForeign(dylib.CurrentVersion, dylib.Name, path.Base(dylib.Name))
+		addForeign(dylib.CompatibilityVersion, dylib.Name, path.Base(dylib.Name))
+	}
+	if slice.Platform != "" {
+		addForeign(slice.MinOS, slice.Platform)
+	}
+	addForeign(slice.SDK, "sdk")
+	for _, tool := range slice.Tools {
+		addForeign(tool.Version, tool.Name)
+	}
+
+	var filtered []Candidate
+	for _, candidate := range candidates {
+		if !mentionsAny(candidate.Context, foreign[candidate.Version]) {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered
+}
+
+// mentionsAny reports whether context contains one of names, ignoring case
+func mentionsAny(context string, names []string) bool {
+	context = strings.ToLower(context)
+	for _, name := range names {
+		if name != "" && strings.Contains(context, strings.ToLower(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeMachOSlice writes the slice's load commands for the text report
+func writeMachOSlice(sb *strings.Builder, slice *MachOSlice) {
+	sb.WriteString(fmt.Sprintf("  Architecture: %s\n", slice.Arch))
+	sb.WriteString(fmt.Sprintf("  Type: %s\n", slice.Type))
+	sb.WriteString(fmt.Sprintf("  Offset: 0x%x\n", slice.Offset))
+	if slice.SourceVersion != "" {
+		sb.WriteString(fmt.Sprintf("  Source Version: %s\n", slice.SourceVersion))
+	}
+	if slice.Platform != "" {
+		sb.WriteString(fmt.Sprintf("  Platform: %s %s (SDK %s)\n", slice.Platform, slice.MinOS, slice.SDK))
+	}
+	if slice.ID != nil {
+		sb.WriteString(fmt.Sprintf("  Install Name: %s %s (compatibility %s)\n", slice.ID.Name, slice.ID.CurrentVersion, slice.ID.CompatibilityVersion))
+	}
+	for _, tool := range slice.Tools {
+		sb.WriteString(fmt.Sprintf("  Tool: %s %s\n", tool.Name, tool.Version))
+	}
+	sb.WriteString("  Linked Libraries:\n")
+	for _, dylib := range slice.Dylibs {
+		sb.WriteString(fmt.Sprintf("    - %s %s (compatibility %s)\n", dylib.Name, dylib.CurrentVersion, dylib.CompatibilityVersion))
+	}
+}
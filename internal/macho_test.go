@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestMachOFilterDependencyVersions(t *testing.T) {
+	slice := &MachOSlice{
+		Platform: "macOS",
+		MinOS:    "11.0",
+		SDK:      "14.0",
+		Dylibs: []MachODylib{
+			{Name: "/usr/lib/libSystem.B.dylib", CurrentVersion: "1345.100.2", CompatibilityVersion: "1.0.0"},
+		},
+		Tools: []MachOTool{{Name: "ld", Version: "1015.7"}},
+	}
+
+	candidates := []Candidate{
+		{Version: "1.0.0", Pattern: "Standard Version Declaration", Context: "mytool version 1.0.0"},
+		{Version: "1.0.0", Pattern: "Semantic Version", Context: "linked against libSystem.B.dylib 1.0.0"},
+		{Version: "11.0", Pattern: "Release Keyword Version", Context: "requires macOS 11.0"},
+		{Version: "14.0", Pattern: "Semantic Version", Context: "MacOSX14.0.sdk"},
+		{Version: "1015.7", Pattern: "Semantic Version", Context: "ld-1015.7"},
+		{Version: "11.0", Pattern: "Semantic Version", Context: "release 11.0"},
+	}
+
+	filtered := slice.FilterDependencyVersions(candidates)
+
+	var kept []string
+	for _, candidate := range filtered {
+		kept = append(kept, candidate.Context)
+	}
+	want := []string{"mytool version 1.0.0", "release 11.0"}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %q, want %q", kept, want)
+	}
+	for i := range want {
+		if kept[i] != want[i] {
+			t.Errorf("kept[%d] = %q, want %q", i, kept[i], want[i])
+		}
+	}
+}
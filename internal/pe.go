@@ -0,0 +1,436 @@
+package internal
+
+import (
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"binary-version-analyzer/versions"
+)
+
+const (
+	// peResourceDirectory is the data directory index of the resource table
+	peResourceDirectory = 2
+	// rtVersion is the resource type of VS_VERSIONINFO
+	rtVersion = 16
+	// fixedFileInfoSignature starts every VS_FIXEDFILEINFO
+	fixedFileInfoSignature = 0xFEEF04BD
+	// maxResourceDepth guards against resource directories that point back at themselves
+	maxResourceDepth = 3
+)
+
+// PEVersionInfo holds the VS_VERSIONINFO resource of a Windows executable or DLL
+type PEVersionInfo struct {
+	FileVersion         string            `json:"file_version,omitempty" yaml:"file_version,omitempty"`
+	ProductVersion      string            `json:"product_version,omitempty" yaml:"product_version,omitempty"`
+	ProductName         string            `json:"product_name,omitempty" yaml:"product_name,omitempty"`
+	CompanyName         string            `json:"company_name,omitempty" yaml:"company_name,omitempty"`
+	FixedFileVersion    string            `json:"fixed_file_version,omitempty" yaml:"fixed_file_version,omitempty"`       // From VS_FIXEDFILEINFO
+	FixedProductVersion string            `json:"fixed_product_version,omitempty" yaml:"fixed_product_version,omitempty"` // From VS_FIXEDFILEINFO
+	Strings             map[string]string `json:"strings,omitempty" yaml:"strings,omitempty"`                             // Every StringFileInfo entry
+
+	offset int64 // File offset of the resource, for the candidates' evidence
+}
+
+// readyToRunOSMachines are the values .NET ReadyToRun images XOR into the
+// machine field to mark their target OS
+var readyToRunOSMachines = []uint16{0x7B79, 0x4644, 0xADC4, 0x1993, 0x1992} // Linux, Apple, FreeBSD, NetBSD, SunOS
+
+// peFile is a PE file together with the file it was opened from
+type peFile struct {
+	*pe.File
+	file *os.File
+}
+
+func (f *peFile) Close() error {
+	return f.file.Close()
+}
+
+// openPE opens a PE file. ReadyToRun images for other operating systems have
+// their machine field restored, since debug/pe rejects the OS-specific values.
+func openPE(path string) (*peFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %v", path, err)
+	}
+
+	var r io.ReaderAt = file
+	if offset, machine, ok := readyToRunMachine(file); ok {
+		r = &patchedReaderAt{ReaderAt: file, offset: offset, patch: []byte{byte(machine), byte(machine >> 8)}}
+	}
+
+	f, err := pe.NewFile(r)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading PE file: %v", err)
+	}
+	return &peFile{File: f, file: file}, nil
+}
+
+// readyToRunMachine returns the offset of the machine field and the real
+// machine of a ReadyToRun image whose machine is OS-specific
+func readyToRunMachine(r io.ReaderAt) (int64, uint16, bool) {
+	var dos [0x40]byte
+	if _, err := r.ReadAt(dos[:], 0); err != nil || dos[0] != 'M' || dos[1] != 'Z' {
+		return 0, 0, false
+	}
+	offset := int64(binary.LittleEndian.Uint32(dos[0x3c:]))
+
+	var header [6]byte
+	if _, err := r.ReadAt(header[:], offset); err != nil || string(header[:4]) != "PE\x00\x00" {
+		return 0, 0, false
+	}
+	machine := binary.LittleEndian.Uint16(header[4:])
+	for _, target := range readyToRunOSMachines {
+		switch machine ^ target {
+		case pe.IMAGE_FILE_MACHINE_AMD64, pe.IMAGE_FILE_MACHINE_ARM64, pe.IMAGE_FILE_MACHINE_I386, pe.IMAGE_FILE_MACHINE_ARMNT:
+			return offset + 4, machine ^ target, true
+		}
+	}
+	return 0, 0, false
+}
+
+// patchedReaderAt reads through to r, replacing the bytes at offset with patch
+type patchedReaderAt struct {
+	io.ReaderAt
+	offset int64
+	patch  []byte
+}
+
+func (p *patchedReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	n, err := p.ReaderAt.ReadAt(b, off)
+	for i := range p.patch {
+		if pos := p.offset + int64(i) - off; pos >= 0 && pos < int64(n) {
+			b[pos] = p.patch[i]
+		}
+	}
+	return n, err
+}
+
+// versionBlock is one node of the VS_VERSIONINFO tree: VS_VERSIONINFO itself,
+// StringFileInfo, a StringTable, a String or VarFileInfo
+type versionBlock struct {
+	key      string
+	text     bool // The value is UTF-16 text rather than binary data
+	value    []byte
+	children []versionBlock
+}
+
+// ReadPEVersionInfo extracts the VS_VERSIONINFO resource from a PE file.
+// It returns an error for files that are not PE files or have no version resource.
+func (ba *BinaryAnalyzer) ReadPEVersionInfo(path string) (*PEVersionInfo, error) {
+	f, err := openPE(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, offset, err := peVersionResource(f.File)
+	if err != nil {
+		return nil, err
+	}
+
+	root, _, err := parseVersionBlock(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing VS_VERSIONINFO: %v", err)
+	}
+	if root.key != "VS_VERSION_INFO" {
+		return nil, fmt.Errorf("error parsing VS_VERSIONINFO: unexpected key %q", root.key)
+	}
+
+	info := &PEVersionInfo{offset: offset}
+	info.readFixedFileInfo(root.value)
+	for _, child := range root.children {
+		if child.key != "StringFileInfo" {
+			continue
+		}
+		for _, table := range child.children {
+			for _, entry := range table.children {
+				value := decodeUTF16(entry.value)
+				if value == "" {
+					continue
+				}
+				if info.Strings == nil {
+					info.Strings = make(map[string]string)
+				}
+				// The first string table, usually the neutral or English one, wins
+				if _, exists := info.Strings[entry.key]; !exists {
+					info.Strings[entry.key] = value
+				}
+			}
+		}
+	}
+
+	info.FileVersion = info.Strings["FileVersion"]
+	info.ProductVersion = info.Strings["ProductVersion"]
+	info.ProductName = info.Strings["ProductName"]
+	info.CompanyName = info.Strings["CompanyName"]
+	return info, nil
+}
+
+// readFixedFileInfo decodes the numeric versions of a VS_FIXEDFILEINFO
+func (info *PEVersionInfo) readFixedFileInfo(value []byte) {
+	if len(value) < 24 || binary.LittleEndian.Uint32(value) != fixedFileInfoSignature {
+		return
+	}
+	info.FixedFileVersion = fixedVersion(binary.LittleEndian.Uint32(value[8:]), binary.LittleEndian.Uint32(value[12:]))
+	info.FixedProductVersion = fixedVersion(binary.LittleEndian.Uint32(value[16:]), binary.LittleEndian.Uint32(value[20:]))
+}
+
+// fixedVersion formats a version split over two DWORDs, or returns "" for 0.0.0.0
+func fixedVersion(ms, ls uint32) string {
+	if ms == 0 && ls == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", ms>>16, ms&0xffff, ls>>16, ls&0xffff)
+}
+
+// Candidates returns the file and product versions as candidates, the string
+// forms before the numeric ones. Strings such as "10.0.19041.1 (WinBuild.160101.0800)"
+// contribute their leading version.
+func (info *PEVersionInfo) Candidates() []Candidate {
+	context := info.ProductName
+	if info.CompanyName != "" {
+		context = strings.TrimSpace(info.CompanyName + " " + context)
+	}
+
+	var candidates []Candidate
+	seen := make(map[string]bool)
+	add := func(raw, source string) {
+		parsed, ok := parseLeadingVersion(raw)
+		if !ok || seen[parsed.Normalized] {
+			return
+		}
+		seen[parsed.Normalized] = true
+		candidates = append(candidates, Candidate{
+			Version:  parsed.Normalized,
+			Scheme:   parsed.Scheme,
+			Pattern:  "PE " + source,
+			Priority: 1,
+			Offset:   info.offset,
+			Section:  ".rsrc",
+			Encoding: EncodingUTF16LE,
+			Context:  strings.TrimSpace(fmt.Sprintf("%s %s: %s", context, source, raw)),
+			Count:    1,
+		})
+	}
+
+	add(info.ProductVersion, "ProductVersion")
+	add(info.FileVersion, "FileVersion")
+	add(info.FixedProductVersion, "Fixed ProductVersion")
+	add(info.FixedFileVersion, "Fixed FileVersion")
+	return candidates
+}
+
+// parseLeadingVersion parses a version, or the first word of a longer string
+func parseLeadingVersion(raw string) (*versions.Version, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, false
+	}
+	if parsed, err := versions.Parse(raw); err == nil {
+		return parsed, true
+	}
+	// Windows resources often write "1, 2, 3, 4"
+	fields := strings.Fields(strings.ReplaceAll(raw, ", ", "."))
+	if parsed, err := versions.Parse(fields[0]); err == nil {
+		return parsed, true
+	}
+	return nil, false
+}
+
+// peVersionResource finds the first RT_VERSION resource and returns its data
+// and file offset
+func peVersionResource(f *pe.File) ([]byte, int64, error) {
+	dir := peDataDirectory(f, peResourceDirectory)
+	if dir.VirtualAddress == 0 || dir.Size == 0 {
+		return nil, 0, fmt.Errorf("no resources in PE file")
+	}
+	rsrc, _, err := peData(f, dir.VirtualAddress, 0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading resource directory: %v", err)
+	}
+
+	// Levels are type, name and language; only the type is chosen
+	entry, ok := resourceEntry(rsrc, 0, rtVersion)
+	for depth := 0; ok && entry&0x80000000 != 0; depth++ {
+		if depth >= maxResourceDepth {
+			return nil, 0, fmt.Errorf("resource directory is too deep")
+		}
+		entry, ok = resourceEntry(rsrc, entry&0x7fffffff, -1)
+	}
+	if !ok {
+		return nil, 0, fmt.Errorf("no version resource in PE file")
+	}
+
+	// IMAGE_RESOURCE_DATA_ENTRY holds the data's RVA and size
+	if uint64(entry)+8 > uint64(len(rsrc)) {
+		return nil, 0, fmt.Errorf("version resource entry is truncated")
+	}
+	rva := binary.LittleEndian.Uint32(rsrc[entry:])
+	size := binary.LittleEndian.Uint32(rsrc[entry+4:])
+
+	data, offset, err := peData(f, rva, size)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading version resource: %v", err)
+	}
+	return data, offset, nil
+}
+
+// peDataDirectory returns an entry of the optional header's data directory,
+// or a zero entry when the file has none
+func peDataDirectory(f *pe.File, index int) pe.DataDirectory {
+	switch header := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		if len(header.DataDirectory) > index {
+			return header.DataDirectory[index]
+		}
+	case *pe.OptionalHeader64:
+		if len(header.DataDirectory) > index {
+			return header.DataDirectory[index]
+		}
+	}
+	return pe.DataDirectory{}
+}
+
+// peData returns size bytes at a relative virtual address and their file
+// offset. A size of zero returns the rest of the section.
+func peData(f *pe.File, rva, size uint32) ([]byte, int64, error) {
+	section := peSectionAt(f, rva)
+	if section == nil {
+		return nil, 0, fmt.Errorf("address 0x%x is outside every section", rva)
+	}
+	data, err := section.Data()
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading section %s: %v", section.Name, err)
+	}
+
+	start := uint64(rva - section.VirtualAddress)
+	end := uint64(len(data))
+	if size > 0 {
+		end = start + uint64(size)
+	}
+	if start >= uint64(len(data)) || end > uint64(len(data)) {
+		return nil, 0, fmt.Errorf("data at 0x%x is truncated in section %s", rva, section.Name)
+	}
+	return data[start:end], int64(section.Offset) + int64(start), nil
+}
+
+// resourceEntry looks up an entry of the IMAGE_RESOURCE_DIRECTORY at offset
+// and returns its OffsetToData. An id of -1 takes the first entry.
+func resourceEntry(rsrc []byte, offset uint32, id int) (uint32, bool) {
+	if uint64(offset)+16 > uint64(len(rsrc)) {
+		return 0, false
+	}
+	named := binary.LittleEndian.Uint16(rsrc[offset+12:])
+	ids := binary.LittleEndian.Uint16(rsrc[offset+14:])
+
+	for i := 0; i < int(named)+int(ids); i++ {
+		pos := uint64(offset) + 16 + uint64(i)*8
+		if pos+8 > uint64(len(rsrc)) {
+			return 0, false
+		}
+		name := binary.LittleEndian.Uint32(rsrc[pos:])
+		data := binary.LittleEndian.Uint32(rsrc[pos+4:])
+		if id < 0 || (name&0x80000000 == 0 && name == uint32(id)) {
+			return data, true
+		}
+	}
+	return 0, false
+}
+
+// peSectionAt returns the section that holds a relative virtual address
+func peSectionAt(f *pe.File, rva uint32) *pe.Section {
+	for _, section := range f.Sections {
+		size := section.VirtualSize
+		if size == 0 {
+			size = section.Size
+		}
+		if rva >= section.VirtualAddress && rva < section.VirtualAddress+size {
+			return section
+		}
+	}
+	return nil
+}
+
+// parseVersionBlock parses a version block and its children, returning the
+// block and its length in bytes
+func parseVersionBlock(data []byte) (versionBlock, int, error) {
+	if len(data) < 6 {
+		return versionBlock{}, 0, fmt.Errorf("block is truncated")
+	}
+	length := int(binary.LittleEndian.Uint16(data))
+	valueLength := int(binary.LittleEndian.Uint16(data[2:]))
+	block := versionBlock{text: binary.LittleEndian.Uint16(data[4:]) == 1}
+	if length < 6 || length > len(data) {
+		return versionBlock{}, 0, fmt.Errorf("invalid block length %d", length)
+	}
+	data = data[:length]
+
+	// The key is a NUL-terminated UTF-16 string, followed by padding to 32 bits
+	pos := 6
+	for pos+1 < length && (data[pos] != 0 || data[pos+1] != 0) {
+		pos += 2
+	}
+	block.key = decodeUTF16(data[6:pos])
+	pos = align4(pos + 2)
+
+	// Text values are measured in characters, binary values in bytes
+	if block.text {
+		valueLength *= 2
+	}
+	if pos+valueLength > length {
+		valueLength = length - pos
+	}
+	if valueLength > 0 {
+		block.value = data[pos : pos+valueLength]
+		pos = align4(pos + valueLength)
+	}
+
+	for pos < length {
+		child, childLength, err := parseVersionBlock(data[pos:])
+		if err != nil {
+			break
+		}
+		block.children = append(block.children, child)
+		pos = align4(pos + childLength)
+	}
+	return block, length, nil
+}
+
+// decodeUTF16 decodes little-endian UTF-16 up to the first NUL
+func decodeUTF16(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		unit := binary.LittleEndian.Uint16(data[i:])
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+	return strings.TrimSpace(string(utf16.Decode(units)))
+}
+
+// writePEVersionInfo writes the version resource for the text report
+func writePEVersionInfo(sb *strings.Builder, info *PEVersionInfo) {
+	for _, field := range []struct{ name, value string }{
+		{"File Version", info.FileVersion},
+		{"Product Version", info.ProductVersion},
+		{"Product Name", info.ProductName},
+		{"Company Name", info.CompanyName},
+		{"Fixed File Version", info.FixedFileVersion},
+		{"Fixed Product Version", info.FixedProductVersion},
+	} {
+		if field.value != "" {
+			sb.WriteString(fmt.Sprintf("  %s: %s\n", field.name, field.value))
+		}
+	}
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"bytes"
+	"compress/zlib"
+	"debug/elf"
+	"debug/macho"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"binary-version-analyzer/versions"
+)
+
+const (
+	// rustDepSection is the section cargo auditable stores the dependency tree in
+	rustDepSection = ".dep-v0"
+	// maxRustDepSize bounds the decompressed dependency tree
+	maxRustDepSize = 64 << 20
+)
+
+// rustcVersionPattern matches the toolchain string rustc writes into .comment,
+// e.g. "rustc version 1.75.0 (82e1608df 2023-12-21)"
+var rustcVersionPattern = regexp.MustCompile(`\brustc(?: version)? (\d+\.\d+\.\d+(?:-(?:beta|nightly)(?:\.\d+)?)?) \(`)
+
+// RustInfo holds what a Rust binary records about its crate and toolchain
+type RustInfo struct {
+	Crate        string      `json:"crate,omitempty" yaml:"crate,omitempty"`
+	Version      string      `json:"version,omitempty" yaml:"version,omitempty"`
+	Source       string      `json:"source,omitempty" yaml:"source,omitempty"`
+	RustcVersion string      `json:"rustc_version,omitempty" yaml:"rustc_version,omitempty"`
+	Dependencies []RustCrate `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+
+	offset int64 // File offset of the .dep-v0 section, for the candidate's evidence
+}
+
+// RustCrate is a crate recorded in the cargo auditable dependency tree
+type RustCrate struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version" yaml:"version"`
+	Source  string `json:"source,omitempty" yaml:"source,omitempty"` // crates.io, git, local, registry or other
+	Kind    string `json:"kind,omitempty" yaml:"kind,omitempty"`     // build for build-time dependencies
+}
+
+// rustAudit is the JSON layout of a .dep-v0 section
+type rustAudit struct {
+	Packages []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Source  string `json:"source"`
+		Kind    string `json:"kind"`
+		Root    bool   `json:"root"`
+	} `json:"packages"`
+}
+
+// ReadRustInfo extracts the cargo auditable dependency tree and the rustc
+// version from a Rust binary. It returns an error when the file has neither.
+func (ba *BinaryAnalyzer) ReadRustInfo(path string) (*RustInfo, error) {
+	info := &RustInfo{}
+
+	data, offset, err := readRustDepSection(path)
+	if err == nil {
+		if err := info.readAudit(data); err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", rustDepSection, err)
+		}
+		info.offset = offset
+	}
+
+	info.RustcVersion = readRustcVersion(path)
+	if info.Crate == "" && info.RustcVersion == "" {
+		return nil, fmt.Errorf("no %s section or rustc version", rustDepSection)
+	}
+	return info, nil
+}
+
+// readAudit decompresses the dependency tree and records the root crate and
+// its dependencies
+func (info *RustInfo) readAudit(data []byte) error {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	var audit rustAudit
+	if err := json.NewDecoder(io.LimitReader(zr, maxRustDepSize)).Decode(&audit); err != nil {
+		return err
+	}
+
+	for _, pkg := range audit.Packages {
+		if pkg.Root && info.Crate == "" {
+			info.Crate = pkg.Name
+			info.Version = pkg.Version
+			info.Source = pkg.Source
+			continue
+		}
+		info.Dependencies = append(info.Dependencies, RustCrate{
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			Source:  pkg.Source,
+			Kind:    pkg.Kind,
+		})
+	}
+	if info.Crate == "" {
+		return fmt.Errorf("no root package")
+	}
+	return nil
+}
+
+// readRustDepSection returns the raw .dep-v0 section of an ELF, PE or Mach-O
+// file and its file offset. Universal binaries use the first slice that has one.
+func readRustDepSection(path string) ([]byte, int64, error) {
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		if section := f.Section(rustDepSection); section != nil {
+			data, err := section.Data()
+			return data, int64(section.Offset), err
+		}
+		return nil, 0, fmt.Errorf("no %s section", rustDepSection)
+	}
+
+	if f, err := openPE(path); err == nil {
+		defer f.Close()
+		if section := f.Section(rustDepSection); section != nil {
+			data, err := section.Data()
+			return data, int64(section.Offset), err
+		}
+		return nil, 0, fmt.Errorf("no %s section", rustDepSection)
+	}
+
+	if fat, err := macho.OpenFat(path); err == nil {
+		defer fat.Close()
+		for _, arch := range fat.Arches {
+			if section := arch.Section(rustDepSection); section != nil {
+				data, err := section.Data()
+				return data, int64(arch.Offset) + int64(section.Offset), err
+			}
+		}
+		return nil, 0, fmt.Errorf("no %s section", rustDepSection)
+	}
+
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		if section := f.Section(rustDepSection); section != nil {
+			data, err := section.Data()
+			return data, int64(section.Offset), err
+		}
+	}
+	return nil, 0, fmt.Errorf("no %s section", rustDepSection)
+}
+
+// readRustcVersion finds the rustc version in the .comment section of an ELF
+// file, or "" when there is none. PE and Mach-O files have no .comment
+// section, so their rustc version is not read.
+func readRustcVersion(path string) string {
+	f, err := elf.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	section := f.Section(".comment")
+	if section == nil {
+		return ""
+	}
+	data, err := section.Data()
+	if err != nil {
+		return ""
+	}
+	if match := rustcVersionPattern.FindSubmatch(data); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// Candidates returns the root crate version as a candidate
+func (info *RustInfo) Candidates() []Candidate {
+	parsed, err := versions.Parse(info.Version)
+	if info.Version == "" || err != nil {
+		return nil
+	}
+	return []Candidate{{
+		Version:  parsed.Normalized,
+		Scheme:   parsed.Scheme,
+		Pattern:  "Rust " + rustDepSection,
+		Priority: 1,
+		Offset:   info.offset,
+		Section:  rustDepSection,
+		Encoding: EncodingASCII,
+		Context:  fmt.Sprintf("%s %s", info.Crate, info.Version),
+		Count:    1,
+	}}
+}
+
+// HasCrateVersion reports whether the root crate version was read
+func (info *RustInfo) HasCrateVersion() bool {
+	return len(info.Candidates()) > 0
+}
+
+// FilterDependencyVersions drops candidates that are only versions of crate
+// dependencies or of rustc, so they do not compete with the crate's own version
+func (info *RustInfo) FilterDependencyVersions(candidates []Candidate) []Candidate {
+	foreign := make(map[string]bool)
+	addForeign := func(raw string) {
+		if parsed, err := versions.Parse(raw); raw != "" && err == nil {
+			foreign[parsed.Normalized] = true
+		}
+	}
+	for _, dep := range info.Dependencies {
+		addForeign(dep.Version)
+	}
+	addForeign(info.RustcVersion)
+	for _, candidate := range info.Candidates() {
+		delete(foreign, candidate.Version)
+	}
+
+	var filtered []Candidate
+	for _, candidate := range candidates {
+		if !foreign[candidate.Version] {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered
+}
+
+// writeRustInfo writes the crate, toolchain and dependencies for the text report
+func writeRustInfo(sb *strings.Builder, info *RustInfo) {
+	if info.Crate != "" {
+		sb.WriteString(fmt.Sprintf("  Crate: %s %s\n", info.Crate, info.Version))
+	}
+	if info.RustcVersion != "" {
+		sb.WriteString(fmt.Sprintf("  rustc Version: %s\n", info.RustcVersion))
+	}
+	if len(info.Dependencies) > 0 {
+		sb.WriteString("  Dependencies:\n")
+		for _, dep := range info.Dependencies {
+			sb.WriteString(fmt.Sprintf("    - %s %s\n", dep.Name, dep.Version))
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMinStringLength matches the default of GNU strings
+	DefaultMinStringLength = 4
+	// maxStringRunLength splits very long runs so a single run cannot grow unbounded
+	maxStringRunLength = 4096
+)
+
+// Encoding identifies how a string was stored in a binary
+type Encoding string
+
+const (
+	EncodingASCII   Encoding = "ascii"
+	EncodingUTF16LE Encoding = "utf-16le"
+	EncodingUTF16BE Encoding = "utf-16be"
+)
+
+// StringRun is a run of printable characters found in a binary
+type StringRun struct {
+	Offset   int64    // File offset of the first byte of the run
+	Text     string   // The printable characters, converted to UTF-8
+	Encoding Encoding // How the characters were stored
+}
+
+// runState tracks one in-progress run for a single encoding and alignment
+type runState struct {
+	encoding Encoding
+	text     []byte // UTF-8 encoded characters
+	chars    int
+	start    int64
+
+	// Range of the previous run, used to drop misaligned UTF-16 shadows
+	lastStart int64
+	lastEnd   int64
+
+	// The UTF-16 state with the other byte order and alignment
+	opposite *runState
+}
+
+// stringExtractor decodes ASCII and UTF-16 runs from a byte stream in a single pass
+type stringExtractor struct {
+	minLength int
+	fn        func(StringRun) bool
+	stopped   bool
+
+	ascii runState
+	// UTF-16 runs indexed by alignment, since strings can start at even or odd offsets
+	utf16LE [2]runState
+	utf16BE [2]runState
+}
+
+func newStringExtractor(minLength int, fn func(StringRun) bool) *stringExtractor {
+	e := &stringExtractor{minLength: minLength, fn: fn}
+	e.ascii.encoding = EncodingASCII
+	for align := 0; align < 2; align++ {
+		e.utf16LE[align].encoding = EncodingUTF16LE
+		e.utf16LE[align].opposite = &e.utf16BE[1-align]
+		e.utf16BE[align].encoding = EncodingUTF16BE
+		e.utf16BE[align].opposite = &e.utf16LE[1-align]
+	}
+	return e
+}
+
+// ExtractStrings reads r and calls fn for every run of at least minLength
+// printable characters, the same way GNU strings does. Besides ASCII it also
+// decodes UTF-16LE and UTF-16BE runs as used in Windows and Java binaries.
+// baseOffset is the file offset of the first byte of r. Extraction stops
+// early when fn returns false.
+func ExtractStrings(r io.Reader, baseOffset int64, minLength int, fn func(StringRun) bool) error {
+	if minLength < 1 {
+		minLength = DefaultMinStringLength
+	}
+
+	e := newStringExtractor(minLength, fn)
+	reader := bufio.NewReaderSize(r, 64*1024)
+	offset := baseOffset
+	var prev byte
+
+	for !e.stopped {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			e.flush(offset)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// ASCII runs
+		if isPrintableByte(b) {
+			e.append(&e.ascii, rune(b), offset, offset)
+		} else {
+			e.end(&e.ascii, offset)
+		}
+
+		// UTF-16 code units ending at this byte
+		if offset > baseOffset {
+			align := (offset - baseOffset) % 2
+
+			if r, ok := decodeUTF16Unit(prev, b, e.utf16LE[align].chars == 0); ok {
+				e.append(&e.utf16LE[align], r, offset-1, offset)
+			} else {
+				e.end(&e.utf16LE[align], offset)
+			}
+
+			if r, ok := decodeUTF16Unit(b, prev, e.utf16BE[align].chars == 0); ok {
+				e.append(&e.utf16BE[align], r, offset-1, offset)
+			} else {
+				e.end(&e.utf16BE[align], offset)
+			}
+		}
+
+		prev = b
+		offset++
+	}
+
+	return nil
+}
+
+// append adds a character to a run, starting it at start if it is empty
+func (e *stringExtractor) append(state *runState, r rune, start, offset int64) {
+	if state.chars == 0 {
+		state.start = start
+	}
+	state.text = utf8.AppendRune(state.text, r)
+	state.chars++
+
+	if state.chars >= maxStringRunLength {
+		e.end(state, offset+1)
+	}
+}
+
+// end finishes the current run of a state at offset and emits it if it is long enough
+func (e *stringExtractor) end(state *runState, offset int64) {
+	if state.chars == 0 {
+		return
+	}
+
+	if !e.stopped && state.chars >= e.minLength && !state.isShadow(offset) {
+		if !e.fn(StringRun{Offset: state.start, Text: string(state.text), Encoding: state.encoding}) {
+			e.stopped = true
+		}
+	}
+
+	state.lastStart = state.start
+	state.lastEnd = offset
+	state.text = state.text[:0]
+	state.chars = 0
+}
+
+// isShadow reports whether a UTF-16 run ending at offset is a misaligned copy
+// of a run in the opposite byte order. "A\0B\0C\0" decodes as "ABC" in
+// little-endian but also as "BC" in big-endian one byte later; the run that
+// starts first is the real one.
+func (s *runState) isShadow(offset int64) bool {
+	if s.opposite == nil {
+		return false
+	}
+
+	if s.opposite.chars > 0 {
+		return s.opposite.start < s.start
+	}
+	return s.opposite.lastStart < s.start && s.opposite.lastEnd >= offset-1
+}
+
+// flush emits every run still in progress at the end of the stream
+func (e *stringExtractor) flush(offset int64) {
+	e.end(&e.ascii, offset)
+	for align := 0; align < 2; align++ {
+		e.end(&e.utf16LE[align], offset)
+		e.end(&e.utf16BE[align], offset)
+	}
+}
+
+// decodeUTF16Unit decodes a code unit given as low and high byte. Only
+// printable ASCII and Latin-1 characters are accepted, which covers version
+// strings and keeps random binary data from decoding as text. Runs must start
+// with an ASCII character so stray bytes do not steal the start of a run.
+func decodeUTF16Unit(low, high byte, first bool) (rune, bool) {
+	if high != 0 {
+		return 0, false
+	}
+	if isPrintableByte(low) || (low >= 0xA0 && !first) {
+		return rune(low), true
+	}
+	return 0, false
+}
+
+// isPrintableByte reports whether b is printable ASCII or a tab
+func isPrintableByte(b byte) bool {
+	return b == '\t' || (b >= 32 && b <= 126)
+}
@@ -143,17 +143,18 @@ var VersionPatterns = []VersionPattern{
 	},
 	{
 		Name:        "Semantic Version",
-		Pattern:     regexp.MustCompile(`(?i)\b([\d]+\.[\d]+\.[\d]+(?:-[\w.]+)?(?:\+[\w.]+)?)\b`),
-		Description: "Matches semantic versioning format (MAJOR.MINOR.PATCH with optional pre-release and build metadata)",
-		Purpose:     "Captures standard semantic versions used by most modern software",
+		Pattern:     regexp.MustCompile(`(?i)\b([\d]+\.[\d]+\.[\d]+(?:[a-z]{1,2}\b)?(?:-[\w.]+)?(?:\+[\w.]+)?)\b`),
+		Description: "Matches semantic versioning format (MAJOR.MINOR.PATCH with optional letter suffix, pre-release and build metadata)",
+		Purpose:     "Captures standard semantic versions used by most modern software, and OpenSSL-style letter releases",
 		Examples: []string{
 			"1.2.3",
 			"10.15.7",
 			"2.1.0-alpha.1",
 			"1.0.0+20220101",
 			"3.2.1-beta.2+build.123",
+			"OpenSSL 1.1.1w  11 Sep 2023",
 		},
-		Expected: []string{"1.2.3", "10.15.7", "2.1.0-alpha.1", "1.0.0+20220101", "3.2.1-beta.2+build.123"},
+		Expected: []string{"1.2.3", "10.15.7", "2.1.0-alpha.1", "1.0.0+20220101", "3.2.1-beta.2+build.123", "1.1.1w"},
 		Priority: 2,
 	},
 	{
@@ -0,0 +1,207 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultAnthropicVersion is sent as the anthropic-version header when none is configured
+const defaultAnthropicVersion = "2023-06-01"
+
+// AnthropicProvider implements the AIProvider interface for the Anthropic Messages API
+type AnthropicProvider struct {
+	config *AIConfig
+	client *http.Client
+}
+
+// AnthropicRequest represents the request structure for the Messages API
+type AnthropicRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
+}
+
+// AnthropicResponse represents a response from the Messages API. The answer
+// is split into content blocks rather than choices.
+type AnthropicResponse struct {
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Role       string                  `json:"role"`
+	Model      string                  `json:"model"`
+	Content    []AnthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      AnthropicUsage          `json:"usage"`
+}
+
+// AnthropicContentBlock is one block of a Messages API response
+type AnthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// AnthropicUsage reports the tokens used by a request
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// AnthropicErrorResponse represents an error returned by the Messages API
+type AnthropicErrorResponse struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewAnthropicProvider creates a new Anthropic provider with configuration
+func NewAnthropicProvider(config *AIConfig) *AnthropicProvider {
+	return &AnthropicProvider{
+		config: config,
+		client: newHTTPClient(config),
+	}
+}
+
+// GetConfig returns the current configuration
+func (a *AnthropicProvider) GetConfig() *AIConfig {
+	return a.config
+}
+
+// UpdateConfig updates the provider configuration
+func (a *AnthropicProvider) UpdateConfig(config *AIConfig) error {
+	if err := ValidateConfig(config); err != nil {
+		return err
+	}
+	a.config = config
+	a.client = newHTTPClient(config)
+	return nil
+}
+
+// SetModel allows changing the model used by Anthropic
+func (a *AnthropicProvider) SetModel(model string) {
+	a.config.Model = model
+}
+
+// AnalyzeVersions implements the AIProvider interface
+func (a *AnthropicProvider) AnalyzeVersions(binaryName string, candidates []string) (string, error) {
+	return a.AnalyzeVersionsContext(context.Background(), binaryName, candidates)
+}
+
+// AnalyzeVersionsContext implements the AIProvider interface
+func (a *AnthropicProvider) AnalyzeVersionsContext(ctx context.Context, binaryName string, candidates []string) (string, error) {
+	resp, err := a.AnalyzeRequestContext(ctx, &AIRequest{
+		BinaryName: binaryName,
+		Candidates: candidates,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Version, nil
+}
+
+// AnalyzeRequest implements the AIProvider interface
+func (a *AnthropicProvider) AnalyzeRequest(request *AIRequest) (*AIResponse, error) {
+	return a.AnalyzeRequestContext(context.Background(), request)
+}
+
+// AnalyzeRequestContext implements the AIProvider interface. The Messages API has no
+// JSON mode, so the answer relies on the prompt and the fallback parsing.
+func (a *AnthropicProvider) AnalyzeRequestContext(ctx context.Context, request *AIRequest) (*AIResponse, error) {
+	if len(request.Candidates) == 0 {
+		return nil, fmt.Errorf("no version candidates provided")
+	}
+
+	reqBody := AnthropicRequest{
+		Model:  a.config.Model,
+		System: analysisSystemPrompt,
+		Messages: []Message{
+			{
+				Role:    "user",
+				Content: buildAnalysisPrompt(request),
+			},
+		},
+		MaxTokens:   a.config.MaxTokens,
+		Temperature: a.config.Temperature,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/messages", strings.TrimSuffix(a.config.BaseURL, "/"))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	version := a.config.APIVersion
+	if version == "" {
+		version = defaultAnthropicVersion
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", a.config.APIKey)
+	req.Header.Set("anthropic-version", version)
+
+	meter := UsageMeterFromContext(ctx)
+	if err := meter.Allow(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var apiErr AnthropicErrorResponse
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, fmt.Errorf("API request failed with status %d: %s: %s", resp.StatusCode, apiErr.Error.Type, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var anthropicResp AnthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+	usage := meter.Record(a.config, anthropicResp.Usage.InputTokens, anthropicResp.Usage.OutputTokens, time.Since(start))
+
+	text := anthropicResp.Text()
+	if text == "" {
+		return nil, fmt.Errorf("no text in response from Anthropic API (stop reason: %s)", anthropicResp.StopReason)
+	}
+
+	aiResp, err := parseAIResponse(text, a.GetProviderName())
+	if err != nil {
+		return nil, err
+	}
+	aiResp.Usage = &usage
+	return aiResp, nil
+}
+
+// Text joins the text blocks of a response, skipping other block types
+func (r *AnthropicResponse) Text() string {
+	var sb strings.Builder
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// GetProviderName returns the name of the provider
+func (a *AnthropicProvider) GetProviderName() string {
+	return "Anthropic"
+}
@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicAnalyzeRequest(t *testing.T) {
+	const body = `{
+  "id": "msg_01",
+  "type": "message",
+  "role": "assistant",
+  "model": "claude-3-5-haiku-latest",
+  "content": [
+    {"type": "text", "text": "{\"version\": \"3.0.17\", \"confidence\": 0.85,"},
+    {"type": "tool_use", "id": "toolu_01", "name": "ignored", "input": {}},
+    {"type": "text", "text": " \"product\": \"OpenSSL\", \"rationale\": \"Named in the OpenSSL banner\"}"}
+  ],
+  "stop_reason": "end_turn",
+  "usage": {"input_tokens": 210, "output_tokens": 40}
+}`
+
+	config := newAnthropicStandIn(t, http.StatusOK, body, func(r *http.Request, req AnthropicRequest) {
+		if got := r.Header.Get("x-api-key"); got != "sk-ant-test" {
+			t.Errorf("x-api-key = %q, want sk-ant-test", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != "2023-06-01" {
+			t.Errorf("anthropic-version = %q, want 2023-06-01", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization must not be sent, got %q", got)
+		}
+
+		if req.Model != "claude-3-5-haiku-latest" || req.MaxTokens != 200 {
+			t.Errorf("unexpected model or max tokens: %+v", req)
+		}
+		if req.System == "" {
+			t.Error("system prompt must be sent as the top-level system field")
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Role != "user" {
+			t.Fatalf("unexpected messages: %+v", req.Messages)
+		}
+		if !strings.Contains(req.Messages[0].Content, "3.0.17") {
+			t.Errorf("candidates missing from prompt: %q", req.Messages[0].Content)
+		}
+	})
+
+	resp, err := NewAnthropicProvider(config).AnalyzeRequest(&AIRequest{
+		BinaryName: "openssl",
+		Candidates: []string{"3.0.17", "2.36"},
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeRequest: %v", err)
+	}
+
+	if resp.Version != "3.0.17" || resp.Product != "OpenSSL" || resp.Confidence != 0.85 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.ProviderName != "Anthropic" {
+		t.Errorf("provider name = %q, want Anthropic", resp.ProviderName)
+	}
+}
+
+func TestAnthropicErrorResponse(t *testing.T) {
+	const body = `{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`
+	config := newAnthropicStandIn(t, http.StatusUnauthorized, body, nil)
+
+	_, err := NewAnthropicProvider(config).AnalyzeVersions("openssl", []string{"3.0.17"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "authentication_error") || !strings.Contains(err.Error(), "invalid x-api-key") {
+		t.Errorf("error should carry the API error, got: %v", err)
+	}
+}
+
+func TestAnthropicEmptyContent(t *testing.T) {
+	const body = `{"type": "message", "role": "assistant", "content": [], "stop_reason": "max_tokens"}`
+	config := newAnthropicStandIn(t, http.StatusOK, body, nil)
+
+	_, err := NewAnthropicProvider(config).AnalyzeVersions("openssl", []string{"3.0.17"})
+	if err == nil || !strings.Contains(err.Error(), "max_tokens") {
+		t.Errorf("expected an error naming the stop reason, got: %v", err)
+	}
+}
+
+func TestAnthropicAPIKeyFromEnv(t *testing.T) {
+	env := map[string]string{
+		"AI_PROVIDER":       "anthropic",
+		"ANTHROPIC_API_KEY": "sk-ant-env",
+	}
+	resolver := NewConfigResolver(nil, "", nil)
+	resolver.LookupEnv = func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	config, err := LoadConfig(resolver)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if config.Provider != ProviderAnthropic || config.APIKey != "sk-ant-env" {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	delete(env, "ANTHROPIC_API_KEY")
+	if _, err := LoadConfig(resolver); err == nil || !strings.Contains(err.Error(), "ANTHROPIC_API_KEY") {
+		t.Errorf("expected ANTHROPIC_API_KEY to be required, got: %v", err)
+	}
+}
@@ -0,0 +1,365 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCacheTTL is how long a cached verdict is reused
+	DefaultCacheTTL = 7 * 24 * time.Hour
+	// DefaultCacheMaxSize caps the size of the cache directory in bytes
+	DefaultCacheMaxSize = 100 << 20
+
+	cacheDirName   = "binary-version-analyzer"
+	cacheEntryExt  = ".json"
+	cacheTmpPrefix = ".tmp-"
+)
+
+// DefaultCacheDir returns the verdict cache directory under $XDG_CACHE_HOME,
+// or the platform's user cache directory when it is not set
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding cache directory: %v", err)
+	}
+	return filepath.Join(base, cacheDirName, "verdicts"), nil
+}
+
+// VerdictCache stores AI verdicts on disk, one JSON file per key. Entries
+// expire after the TTL, and the oldest entries are dropped once the directory
+// grows past the size cap.
+type VerdictCache struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+}
+
+// CacheStats describes the contents of a verdict cache
+type CacheStats struct {
+	Dir     string
+	Entries int
+	Expired int
+	Size    int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// cacheEntry is the file format of a cached verdict
+type cacheEntry struct {
+	Key      string     `json:"key"`
+	Identity string     `json:"identity"`
+	Response AIResponse `json:"response"`
+}
+
+// cacheFile is a cache entry as found on disk
+type cacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// NewVerdictCache creates a cache in dir. A ttl or maxSize of zero uses the default.
+func NewVerdictCache(dir string, ttl time.Duration, maxSize int64) *VerdictCache {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultCacheMaxSize
+	}
+	return &VerdictCache{dir: dir, ttl: ttl, maxSize: maxSize}
+}
+
+// Dir returns the cache directory
+func (c *VerdictCache) Dir() string {
+	return c.dir
+}
+
+// CacheKey derives the cache key of a request from the binary's SHA-256, the
+// candidate list, the provider identity and the prompt template version
+func CacheKey(req *AIRequest, identity string) string {
+	data, _ := json.Marshal(struct {
+		BinarySHA256  string   `json:"binary_sha256"`
+		Candidates    []string `json:"candidates"`
+		Identity      string   `json:"identity"`
+		PromptVersion string   `json:"prompt_version"`
+	}{req.BinarySHA256, req.Candidates, identity, PromptVersion})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CacheIdentity names the providers and models of a configuration, including
+// its fallbacks, e.g. "groq:llama-3.3-70b-versatile,heuristic:heuristic-ranker"
+func CacheIdentity(config *AIConfig) string {
+	members := []string{fmt.Sprintf("%s:%s", config.Provider, config.Model)}
+	for _, fallback := range config.Fallbacks {
+		members = append(members, fmt.Sprintf("%s:%s", fallback.Provider, fallback.Model))
+	}
+	return strings.Join(members, ",")
+}
+
+// Get returns the cached verdict for key if it has not expired
+func (c *VerdictCache) Get(key string) (*AIResponse, bool) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if time.Since(info.ModTime()) > c.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil, false
+	}
+	return &entry.Response, true
+}
+
+// Put stores a verdict, then drops the oldest entries if the cache is over its size cap
+func (c *VerdictCache) Put(key, identity string, resp *AIResponse) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("error creating cache directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(cacheEntry{Key: key, Identity: identity, Response: *resp}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling cache entry: %v", err)
+	}
+
+	// Write to a temporary file first so parallel runs never read half an entry
+	tmp, err := os.CreateTemp(c.dir, cacheTmpPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("error writing cache entry: %v", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing cache entry: %v", err)
+	}
+
+	_, err = c.enforceSize()
+	return err
+}
+
+// Stats reports the number, age and size of the cached verdicts
+func (c *VerdictCache) Stats() (*CacheStats, error) {
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &CacheStats{Dir: c.dir, Entries: len(files)}
+	for _, file := range files {
+		stats.Size += file.size
+		if c.expired(file) {
+			stats.Expired++
+		}
+		if stats.Oldest.IsZero() || file.modTime.Before(stats.Oldest) {
+			stats.Oldest = file.modTime
+		}
+		if file.modTime.After(stats.Newest) {
+			stats.Newest = file.modTime
+		}
+	}
+	return stats, nil
+}
+
+// Clear removes every cached verdict and returns how many were removed
+func (c *VerdictCache) Clear() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	return c.remove(files)
+}
+
+// Prune removes expired verdicts, then the oldest ones while the cache is
+// over its size cap, and returns how many were removed
+func (c *VerdictCache) Prune() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+
+	var expired []cacheFile
+	for _, file := range files {
+		if c.expired(file) {
+			expired = append(expired, file)
+		}
+	}
+	removed, err := c.remove(expired)
+	if err != nil {
+		return removed, err
+	}
+
+	evicted, err := c.enforceSize()
+	return removed + evicted, err
+}
+
+// enforceSize removes the oldest entries until the cache fits its size cap
+func (c *VerdictCache) enforceSize() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+
+	// files is sorted oldest first
+	var evict []cacheFile
+	for _, file := range files {
+		if total <= c.maxSize {
+			break
+		}
+		evict = append(evict, file)
+		total -= file.size
+	}
+	return c.remove(evict)
+}
+
+// files lists the cache entries, oldest first. A missing directory is an empty cache.
+func (c *VerdictCache) files() ([]cacheFile, error) {
+	entries, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading cache directory: %v", err)
+	}
+
+	var files []cacheFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), cacheEntryExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed by a parallel run
+		}
+		files = append(files, cacheFile{
+			path:    filepath.Join(c.dir, entry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	return files, nil
+}
+
+func (c *VerdictCache) remove(files []cacheFile) (int, error) {
+	removed := 0
+	for _, file := range files {
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("error removing cache entry: %v", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (c *VerdictCache) expired(file cacheFile) bool {
+	return time.Since(file.modTime) > c.ttl
+}
+
+func (c *VerdictCache) path(key string) string {
+	return filepath.Join(c.dir, key+cacheEntryExt)
+}
+
+// CachingProvider serves verdicts from a VerdictCache and stores new ones.
+// Requests without a binary SHA-256 are passed through, and answers from a
+// chain's fallback are not cached so the preferred provider is asked again.
+type CachingProvider struct {
+	provider AIProvider
+	cache    *VerdictCache
+	identity string
+}
+
+// NewCachingProvider creates a cache layer around provider. identity names
+// the providers and models behind it, see CacheIdentity.
+func NewCachingProvider(provider AIProvider, cache *VerdictCache, identity string) *CachingProvider {
+	return &CachingProvider{
+		provider: provider,
+		cache:    cache,
+		identity: identity,
+	}
+}
+
+// AnalyzeVersions implements the AIProvider interface
+func (c *CachingProvider) AnalyzeVersions(binaryName string, candidates []string) (string, error) {
+	return c.AnalyzeVersionsContext(context.Background(), binaryName, candidates)
+}
+
+// AnalyzeVersionsContext implements the AIProvider interface
+func (c *CachingProvider) AnalyzeVersionsContext(ctx context.Context, binaryName string, candidates []string) (string, error) {
+	resp, err := c.AnalyzeRequestContext(ctx, &AIRequest{
+		BinaryName: binaryName,
+		Candidates: candidates,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Version, nil
+}
+
+// AnalyzeRequest implements the AIProvider interface
+func (c *CachingProvider) AnalyzeRequest(req *AIRequest) (*AIResponse, error) {
+	return c.AnalyzeRequestContext(context.Background(), req)
+}
+
+// AnalyzeRequestContext implements the AIProvider interface. Cached verdicts
+// are returned with Cached set and without usage, as they cost no call.
+func (c *CachingProvider) AnalyzeRequestContext(ctx context.Context, req *AIRequest) (*AIResponse, error) {
+	if req.BinarySHA256 == "" {
+		return c.provider.AnalyzeRequestContext(ctx, req)
+	}
+
+	key := CacheKey(req, c.identity)
+	if resp, ok := c.cache.Get(key); ok {
+		resp.Cached = true
+		resp.Usage = nil
+		return resp, nil
+	}
+
+	resp, err := c.provider.AnalyzeRequestContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cache is best effort, a failed write only costs a repeated request
+	if len(resp.Skipped) == 0 {
+		c.cache.Put(key, c.identity, resp)
+	}
+	return resp, nil
+}
+
+// GetProviderName returns the name of the wrapped provider
+func (c *CachingProvider) GetProviderName() string {
+	return c.provider.GetProviderName()
+}
@@ -0,0 +1,146 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SkippedProvider records why a provider in a chain did not answer
+type SkippedProvider struct {
+	Provider string `json:"provider" yaml:"provider"`
+	Reason   string `json:"reason" yaml:"reason"`
+}
+
+// ChainError is returned when no provider in a chain answered. It unwraps to
+// the error of the last provider, so an UnverifiedVersionError from the end
+// of the chain is still reported as an unknown version.
+type ChainError struct {
+	Skipped []SkippedProvider
+	last    error
+}
+
+func (e *ChainError) Error() string {
+	reasons := make([]string, len(e.Skipped))
+	for i, skipped := range e.Skipped {
+		reasons[i] = fmt.Sprintf("%s: %s", skipped.Provider, skipped.Reason)
+	}
+	return fmt.Sprintf("all providers failed (%s)", strings.Join(reasons, "; "))
+}
+
+func (e *ChainError) Unwrap() error {
+	return e.last
+}
+
+// chainMember is one provider of a chain. Providers whose configuration was
+// unusable stay in the chain with the error, so the reason is reported.
+type chainMember struct {
+	name     string
+	model    string
+	provider AIProvider
+	err      error
+}
+
+// ChainProvider tries an ordered list of providers and returns the first
+// answer. Errors and timeouts of a provider move on to the next one.
+type ChainProvider struct {
+	members []chainMember
+}
+
+// AnalyzeVersions implements the AIProvider interface
+func (c *ChainProvider) AnalyzeVersions(binaryName string, candidates []string) (string, error) {
+	return c.AnalyzeVersionsContext(context.Background(), binaryName, candidates)
+}
+
+// AnalyzeVersionsContext implements the AIProvider interface
+func (c *ChainProvider) AnalyzeVersionsContext(ctx context.Context, binaryName string, candidates []string) (string, error) {
+	resp, err := c.AnalyzeRequestContext(ctx, &AIRequest{
+		BinaryName: binaryName,
+		Candidates: candidates,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Version, nil
+}
+
+// AnalyzeRequest implements the AIProvider interface
+func (c *ChainProvider) AnalyzeRequest(req *AIRequest) (*AIResponse, error) {
+	return c.AnalyzeRequestContext(context.Background(), req)
+}
+
+// AnalyzeRequestContext implements the AIProvider interface. The response
+// lists the providers that were skipped before one answered. A cancelled ctx
+// or an exhausted cost budget stops the chain instead of moving on.
+func (c *ChainProvider) AnalyzeRequestContext(ctx context.Context, req *AIRequest) (*AIResponse, error) {
+	var skipped []SkippedProvider
+	var last error
+
+	for _, member := range c.members {
+		if member.err != nil {
+			skipped = append(skipped, SkippedProvider{Provider: member.name, Reason: member.err.Error()})
+			last = member.err
+			continue
+		}
+
+		resp, err := member.provider.AnalyzeRequestContext(ctx, req)
+		if err != nil && ctx.Err() != nil {
+			return nil, fmt.Errorf("%s: %v", member.name, ctx.Err())
+		}
+		var budgetErr *BudgetExceededError
+		if errors.As(err, &budgetErr) {
+			return nil, err
+		}
+		if err != nil {
+			skipped = append(skipped, SkippedProvider{Provider: member.name, Reason: err.Error()})
+			last = err
+			continue
+		}
+
+		if resp.ProviderName == "" {
+			resp.ProviderName = member.name
+		}
+		if resp.Model == "" {
+			resp.Model = member.model
+		}
+		resp.Skipped = skipped
+		return resp, nil
+	}
+
+	return nil, &ChainError{Skipped: skipped, last: last}
+}
+
+// GetProviderName returns the names of the chained providers in order
+func (c *ChainProvider) GetProviderName() string {
+	names := make([]string, len(c.members))
+	for i, member := range c.members {
+		names[i] = member.name
+	}
+	return strings.Join(names, " → ")
+}
+
+// parseProviderChain parses a comma-separated, ordered list of providers
+func parseProviderChain(value string) ([]AIProviderType, error) {
+	var chain []AIProviderType
+	seen := make(map[AIProviderType]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		providerType, err := parseProviderType(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[providerType] {
+			return nil, fmt.Errorf("provider %s is listed more than once", providerType)
+		}
+		seen[providerType] = true
+		chain = append(chain, providerType)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no AI provider given")
+	}
+	return chain, nil
+}
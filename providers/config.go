@@ -2,8 +2,6 @@ package providers
 
 import (
 	"fmt"
-	"os"
-	"strconv"
 	"strings"
 )
 
@@ -16,134 +14,195 @@ type AIConfig struct {
 	MaxTokens   int            `json:"max_tokens"`
 	BaseURL     string         `json:"base_url,omitempty"`
 	Timeout     int            `json:"timeout,omitempty"` // in seconds
+	OrgID       string         `json:"org_id,omitempty"`  // OpenAI organization
+	Offline     bool           `json:"offline,omitempty"` // refuse providers that need network access
+
+	ValidationRetries int `json:"validation_retries"` // re-prompts after an answer that is not a candidate
+	MaxAttempts       int `json:"max_attempts"`       // sends of a request that failed with a retryable error
+	RetryDeadline     int `json:"retry_deadline"`     // seconds all attempts of a request may take
+
+	// Settings for Azure OpenAI, whose endpoint is BaseURL
+	Deployment string `json:"deployment,omitempty"`  // defaults to the model name
+	APIVersion string `json:"api_version,omitempty"` // Azure api-version, or the anthropic-version header
+
+	// Settings for OpenAI-compatible gateways
+	ChatPath   string                 `json:"chat_path,omitempty"`   // appended to BaseURL
+	AuthHeader string                 `json:"auth_header,omitempty"` // header carrying the API key
+	AuthPrefix string                 `json:"auth_prefix,omitempty"` // put before the key, e.g. "Bearer"
+	Headers    map[string]string      `json:"headers,omitempty"`     // sent with every request
+	ExtraBody  map[string]interface{} `json:"extra_body,omitempty"`  // merged into the request body
+
+	// Providers tried in order when this one fails, e.g. AI_PROVIDER=groq,openai,heuristic
+	Fallbacks []*AIConfig `json:"fallbacks,omitempty"`
 }
 
+// DefaultMaxTokensLimit is the max tokens ceiling of hosted providers
+const DefaultMaxTokensLimit = 4096
+
+// localRetryDeadline leaves room for retries after the long timeout of local models
+const localRetryDeadline = 300
+
 // DefaultConfigs provides default configurations for each provider
 var DefaultConfigs = map[AIProviderType]AIConfig{
 	ProviderGroq: {
 		Provider:    ProviderGroq,
 		Model:       "llama-3.3-70b-versatile",
 		Temperature: 0.1,
-		MaxTokens:   50,
+		MaxTokens:   200,
 		BaseURL:     "https://api.groq.com/openai/v1",
 		Timeout:     30,
+
+		ValidationRetries: DefaultValidationRetries,
+		MaxAttempts:       DefaultMaxAttempts,
+		RetryDeadline:     DefaultRetryDeadline,
 	},
 	ProviderOpenAI: {
 		Provider:    ProviderOpenAI,
 		Model:       "gpt-4o-mini",
 		Temperature: 0.1,
-		MaxTokens:   50,
+		MaxTokens:   200,
 		BaseURL:     "https://api.openai.com/v1",
 		Timeout:     30,
+
+		ValidationRetries: DefaultValidationRetries,
+		MaxAttempts:       DefaultMaxAttempts,
+		RetryDeadline:     DefaultRetryDeadline,
 	},
-}
+	ProviderAzure: {
+		Provider:    ProviderAzure,
+		Model:       "gpt-4o-mini",
+		Temperature: 0.1,
+		MaxTokens:   200,
+		Timeout:     30,
 
-// LoadConfigFromEnv loads AI configuration from environment variables
-func LoadConfigFromEnv() (*AIConfig, error) {
-	// Determine provider
-	providerStr := strings.ToLower(os.Getenv("AI_PROVIDER"))
-	if providerStr == "" {
-		providerStr = "groq" // default
-	}
+		ValidationRetries: DefaultValidationRetries,
+		MaxAttempts:       DefaultMaxAttempts,
+		RetryDeadline:     DefaultRetryDeadline,
 
-	var providerType AIProviderType
-	switch providerStr {
-	case "groq":
-		providerType = ProviderGroq
-	case "openai":
-		providerType = ProviderOpenAI
-	default:
-		return nil, fmt.Errorf("unsupported AI provider: %s", providerStr)
-	}
+		// JSON mode needs 2023-12-01-preview or later
+		APIVersion: "2024-06-01",
+	},
+	ProviderAnthropic: {
+		Provider:    ProviderAnthropic,
+		Model:       "claude-3-5-haiku-latest",
+		Temperature: 0.1,
+		MaxTokens:   200,
+		BaseURL:     "https://api.anthropic.com",
+		Timeout:     30,
 
-	// Start with default config
-	config := DefaultConfigs[providerType]
+		ValidationRetries: DefaultValidationRetries,
+		MaxAttempts:       DefaultMaxAttempts,
+		RetryDeadline:     DefaultRetryDeadline,
 
-	// Load API key
-	var apiKey string
-	switch providerType {
-	case ProviderGroq:
-		apiKey = os.Getenv("GROQ_API_KEY")
-		if apiKey == "" {
-			return nil, fmt.Errorf("GROQ_API_KEY environment variable is required")
-		}
-	case ProviderOpenAI:
-		apiKey = os.Getenv("OPENAI_API_KEY")
-		if apiKey == "" {
-			return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required")
-		}
-	}
-	config.APIKey = apiKey
+		APIVersion: "2023-06-01",
+	},
+	ProviderOllama: {
+		Provider:    ProviderOllama,
+		Model:       "llama3.2",
+		Temperature: 0.1,
+		MaxTokens:   200,
+		BaseURL:     "http://localhost:11434",
+		Timeout:     120, // local models can be slow to load
 
-	// Override with environment variables if present
-	if model := os.Getenv("AI_MODEL"); model != "" {
-		config.Model = model
-	}
+		ValidationRetries: DefaultValidationRetries,
+		MaxAttempts:       DefaultMaxAttempts,
+		RetryDeadline:     localRetryDeadline,
+	},
+	ProviderOpenAICompatible: {
+		Provider:    ProviderOpenAICompatible,
+		Model:       "default",
+		Temperature: 0.1,
+		MaxTokens:   200,
+		BaseURL:     "http://localhost:8080",
+		Timeout:     120,
 
-	if tempStr := os.Getenv("AI_TEMPERATURE"); tempStr != "" {
-		if temp, err := strconv.ParseFloat(tempStr, 64); err == nil {
-			if temp >= 0.0 && temp <= 2.0 {
-				config.Temperature = temp
-			} else {
-				return nil, fmt.Errorf("AI_TEMPERATURE must be between 0.0 and 2.0, got: %f", temp)
-			}
-		} else {
-			return nil, fmt.Errorf("invalid AI_TEMPERATURE value: %s", tempStr)
-		}
-	}
+		ValidationRetries: DefaultValidationRetries,
+		MaxAttempts:       DefaultMaxAttempts,
+		RetryDeadline:     localRetryDeadline,
 
-	if tokensStr := os.Getenv("AI_MAX_TOKENS"); tokensStr != "" {
-		if tokens, err := strconv.Atoi(tokensStr); err == nil {
-			if tokens > 0 && tokens <= 4096 {
-				config.MaxTokens = tokens
-			} else {
-				return nil, fmt.Errorf("AI_MAX_TOKENS must be between 1 and 4096, got: %d", tokens)
-			}
-		} else {
-			return nil, fmt.Errorf("invalid AI_MAX_TOKENS value: %s", tokensStr)
-		}
-	}
+		ChatPath:   "/v1/chat/completions",
+		AuthHeader: "Authorization",
+		AuthPrefix: "Bearer",
+	},
+	ProviderHeuristic: {
+		Provider:    ProviderHeuristic,
+		Model:       "heuristic-ranker",
+		Temperature: 0.0,
+		MaxTokens:   50,
+		Timeout:     30,
 
-	if baseURL := os.Getenv("AI_BASE_URL"); baseURL != "" {
-		config.BaseURL = baseURL
-	}
+		ValidationRetries: DefaultValidationRetries,
+		MaxAttempts:       DefaultMaxAttempts,
+		RetryDeadline:     DefaultRetryDeadline,
+	},
+}
 
-	if timeoutStr := os.Getenv("AI_TIMEOUT"); timeoutStr != "" {
-		if timeout, err := strconv.Atoi(timeoutStr); err == nil {
-			if timeout > 0 && timeout <= 300 {
-				config.Timeout = timeout
-			} else {
-				return nil, fmt.Errorf("AI_TIMEOUT must be between 1 and 300 seconds, got: %d", timeout)
-			}
-		} else {
-			return nil, fmt.Errorf("invalid AI_TIMEOUT value: %s", timeoutStr)
-		}
+// Profile holds provider settings from a named config file profile. Empty
+// fields leave the provider defaults in place.
+type Profile struct {
+	Provider          string   `yaml:"provider"`
+	APIKey            string   `yaml:"api_key"`
+	Model             string   `yaml:"model"`
+	Temperature       *float64 `yaml:"temperature"`
+	MaxTokens         int      `yaml:"max_tokens"`
+	BaseURL           string   `yaml:"base_url"`
+	OrgID             string   `yaml:"org_id"`
+	Timeout           int      `yaml:"timeout"`
+	ValidationRetries *int     `yaml:"validation_retries"`
+	MaxAttempts       int      `yaml:"max_attempts"`
+	RetryDeadline     int      `yaml:"retry_deadline"`
+	Offline           bool     `yaml:"offline"`
+
+	// Azure OpenAI
+	Deployment string `yaml:"deployment"`
+	APIVersion string `yaml:"api_version"`
+
+	// OpenAI-compatible gateways; an explicitly empty auth_prefix sends the bare key
+	ChatPath   string                 `yaml:"chat_path"`
+	AuthHeader string                 `yaml:"auth_header"`
+	AuthPrefix *string                `yaml:"auth_prefix"`
+	Headers    map[string]string      `yaml:"headers"`
+	ExtraBody  map[string]interface{} `yaml:"extra_body"`
+}
+
+// LoadConfigFromEnv loads AI configuration from environment variables
+func LoadConfigFromEnv() (*AIConfig, error) {
+	return LoadConfig(NewConfigResolver(nil, "", nil))
+}
+
+// LoadConfig resolves the AI configuration and checks that providers which
+// need an API key have one. In a chain, providers without a key are skipped
+// when the analysis runs instead.
+func LoadConfig(resolver *ConfigResolver) (*AIConfig, error) {
+	config, _, err := resolver.Resolve()
+	if err != nil {
+		return nil, err
 	}
 
-	return &config, nil
+	if len(config.Fallbacks) == 0 && config.APIKey == "" && RequiresAPIKey(config.Provider) {
+		return nil, fmt.Errorf("%s environment variable is required", GetProviderSpecificEnvVars(config.Provider)["API_KEY"])
+	}
+	return config, nil
 }
 
 // GetProviderSpecificEnvVars returns provider-specific environment variable names
 func GetProviderSpecificEnvVars(providerType AIProviderType) map[string]string {
-	switch providerType {
-	case ProviderGroq:
-		return map[string]string{
-			"API_KEY": "GROQ_API_KEY",
-			"MODEL":   "GROQ_MODEL",
-		}
-	case ProviderOpenAI:
-		return map[string]string{
-			"API_KEY": "OPENAI_API_KEY",
-			"MODEL":   "OPENAI_MODEL",
+	vars := make(map[string]string)
+	for _, spec := range providerSettings {
+		if !spec.appliesTo(providerType) {
+			continue
 		}
-	default:
-		return map[string]string{}
+		vars[spec.envSuffix] = providerEnvVars(providerType, spec.envSuffix)[0]
 	}
+	if providerType == ProviderOllama {
+		vars["HOST"] = "OLLAMA_HOST"
+	}
+	return vars
 }
 
 // ValidateConfig validates the AI configuration
 func ValidateConfig(config *AIConfig) error {
-	if config.APIKey == "" {
+	if config.APIKey == "" && RequiresAPIKey(config.Provider) {
 		return fmt.Errorf("API key is required")
 	}
 
@@ -151,18 +210,37 @@ func ValidateConfig(config *AIConfig) error {
 		return fmt.Errorf("model is required")
 	}
 
+	if config.Provider == ProviderAzure && config.BaseURL == "" {
+		return fmt.Errorf("endpoint is required for Azure OpenAI, set AZURE_OPENAI_ENDPOINT or base_url")
+	}
+
 	if config.Temperature < 0.0 || config.Temperature > 2.0 {
 		return fmt.Errorf("temperature must be between 0.0 and 2.0")
 	}
 
-	if config.MaxTokens <= 0 || config.MaxTokens > 4096 {
-		return fmt.Errorf("max tokens must be between 1 and 4096")
+	if limit := MaxTokensLimit(config.Provider); config.MaxTokens <= 0 || (limit > 0 && config.MaxTokens > limit) {
+		if limit == 0 {
+			return fmt.Errorf("max tokens must be positive")
+		}
+		return fmt.Errorf("max tokens must be between 1 and %d", limit)
 	}
 
 	if config.Timeout <= 0 || config.Timeout > 300 {
 		return fmt.Errorf("timeout must be between 1 and 300 seconds")
 	}
 
+	if config.ValidationRetries < 0 || config.ValidationRetries > 5 {
+		return fmt.Errorf("validation retries must be between 0 and 5")
+	}
+
+	if config.MaxAttempts < 1 || config.MaxAttempts > 10 {
+		return fmt.Errorf("max attempts must be between 1 and 10")
+	}
+
+	if config.RetryDeadline <= 0 || config.RetryDeadline > 900 {
+		return fmt.Errorf("retry deadline must be between 1 and 900 seconds")
+	}
+
 	return nil
 }
 
@@ -176,8 +254,85 @@ func PrintConfigInfo(config *AIConfig) {
 	if config.BaseURL != "" {
 		fmt.Printf("   Base URL: %s\n", config.BaseURL)
 	}
+	if config.OrgID != "" {
+		fmt.Printf("   Organization: %s\n", config.OrgID)
+	}
+	if config.Provider == ProviderAzure {
+		fmt.Printf("   Deployment: %s\n", azureDeployment(config))
+	}
+	if config.APIVersion != "" {
+		fmt.Printf("   API Version: %s\n", config.APIVersion)
+	}
 	fmt.Printf("   Timeout: %ds\n", config.Timeout)
-	fmt.Printf("   API Key: %s***\n", config.APIKey[:min(8, len(config.APIKey))])
+	fmt.Printf("   Validation Retries: %d\n", config.ValidationRetries)
+	fmt.Printf("   Max Attempts: %d (within %ds)\n", config.MaxAttempts, config.RetryDeadline)
+	if config.Offline {
+		fmt.Printf("   Offline: yes\n")
+	}
+	if RequiresAPIKey(config.Provider) {
+		fmt.Printf("   API Key: %s\n", MaskAPIKey(config.APIKey))
+	}
+	if len(config.Fallbacks) > 0 {
+		fallbacks := make([]string, len(config.Fallbacks))
+		for i, fallback := range config.Fallbacks {
+			fallbacks[i] = fmt.Sprintf("%s (%s)", fallback.Provider, fallback.Model)
+		}
+		fmt.Printf("   Fallbacks: %s\n", strings.Join(fallbacks, ", "))
+	}
+}
+
+// MaxTokensLimit returns the max tokens ceiling of a provider, or 0 when
+// self-hosted servers decide for themselves
+func MaxTokensLimit(providerType AIProviderType) int {
+	if providerType == ProviderOpenAICompatible {
+		return 0
+	}
+	return DefaultMaxTokensLimit
+}
+
+// parseProviderType converts a provider name to its AIProviderType
+func parseProviderType(name string) (AIProviderType, error) {
+	switch name {
+	case "groq":
+		return ProviderGroq, nil
+	case "openai":
+		return ProviderOpenAI, nil
+	case "openai-compatible":
+		return ProviderOpenAICompatible, nil
+	case "azure":
+		return ProviderAzure, nil
+	case "anthropic":
+		return ProviderAnthropic, nil
+	case "ollama":
+		return ProviderOllama, nil
+	case "heuristic":
+		return ProviderHeuristic, nil
+	default:
+		return "", fmt.Errorf("unsupported AI provider: %s", name)
+	}
+}
+
+// ollamaBaseURL turns an OLLAMA_HOST value such as 127.0.0.1:11434 into a URL
+func ollamaBaseURL(host string) string {
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return strings.TrimSuffix(host, "/")
+}
+
+// MaskAPIKey returns the start of an API key, safe for printing
+func MaskAPIKey(key string) string {
+	return key[:min(8, len(key))] + "***"
+}
+
+// IsTruthy reports whether an environment value enables a boolean setting
+func IsTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
 }
 
 // Helper function for min
@@ -0,0 +1,503 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ResolvedSetting records the effective value of one configuration setting
+// and the source it came from
+type ResolvedSetting struct {
+	Name   string // Setting name as used in config file profiles
+	Value  string
+	Source string // e.g. "flag --model", "env GROQ_MODEL", "profile fast", "default"
+}
+
+// ConfigResolver resolves every AIConfig setting from layered sources, in
+// order of precedence: flag > provider-prefixed env > generic env > profile > default.
+// A provider-prefixed variable such as GROQ_MODEL only applies when Groq is
+// the provider in effect, and beats the generic AI_MODEL.
+//
+// The provider may be a comma-separated chain such as groq,openai,heuristic.
+// Fallback providers share the generic settings, but take per-provider
+// settings like the model and API key only from their own variables.
+type ConfigResolver struct {
+	Flags       map[string]string // Command-line values keyed by setting name
+	Profile     *Profile
+	ProfileName string
+	LookupEnv   func(key string) (string, bool)
+}
+
+// settingSpec describes how one AIConfig setting is resolved
+type settingSpec struct {
+	name      string
+	envSuffix string // Appended to AI_ and to the provider prefix
+	flag      string
+	// perProvider settings come from the profile only when the profile was
+	// written for the provider in effect
+	perProvider bool
+	only        []AIProviderType // Only resolved for these providers when set
+	profile     func(p *Profile) (string, bool)
+	current     func(c *AIConfig) string
+	apply       func(c *AIConfig, value, source string) error
+}
+
+// appliesTo reports whether a setting is resolved for a provider
+func (spec settingSpec) appliesTo(providerType AIProviderType) bool {
+	if len(spec.only) == 0 {
+		return true
+	}
+	for _, only := range spec.only {
+		if only == providerType {
+			return true
+		}
+	}
+	return false
+}
+
+// envAliases lists the variables a provider's own tooling uses for a setting
+var envAliases = map[AIProviderType]map[string]string{
+	ProviderOllama: {
+		"BASE_URL": "OLLAMA_HOST",
+	},
+	ProviderAzure: {
+		"API_KEY":     "AZURE_OPENAI_API_KEY",
+		"BASE_URL":    "AZURE_OPENAI_ENDPOINT",
+		"API_VERSION": "OPENAI_API_VERSION",
+	},
+}
+
+// providerSettings lists the settings resolved after the provider is known
+var providerSettings = []settingSpec{
+	{
+		name: "api_key", envSuffix: "API_KEY", perProvider: true,
+		profile: func(p *Profile) (string, bool) { return p.APIKey, p.APIKey != "" },
+		current: func(c *AIConfig) string { return c.APIKey },
+		apply:   func(c *AIConfig, value, source string) error { c.APIKey = value; return nil },
+	},
+	{
+		name: "model", envSuffix: "MODEL", flag: "model", perProvider: true,
+		profile: func(p *Profile) (string, bool) { return p.Model, p.Model != "" },
+		current: func(c *AIConfig) string { return c.Model },
+		apply:   func(c *AIConfig, value, source string) error { c.Model = value; return nil },
+	},
+	{
+		name: "base_url", envSuffix: "BASE_URL", flag: "base-url", perProvider: true,
+		profile: func(p *Profile) (string, bool) { return p.BaseURL, p.BaseURL != "" },
+		current: func(c *AIConfig) string { return c.BaseURL },
+		apply:   func(c *AIConfig, value, source string) error { c.BaseURL = value; return nil },
+	},
+	{
+		name: "org_id", envSuffix: "ORG_ID", perProvider: true,
+		profile: func(p *Profile) (string, bool) { return p.OrgID, p.OrgID != "" },
+		current: func(c *AIConfig) string { return c.OrgID },
+		apply:   func(c *AIConfig, value, source string) error { c.OrgID = value; return nil },
+	},
+	{
+		name: "temperature", envSuffix: "TEMPERATURE", flag: "temperature",
+		profile: func(p *Profile) (string, bool) {
+			if p.Temperature == nil {
+				return "", false
+			}
+			return strconv.FormatFloat(*p.Temperature, 'f', -1, 64), true
+		},
+		current: func(c *AIConfig) string { return strconv.FormatFloat(c.Temperature, 'f', 2, 64) },
+		apply: func(c *AIConfig, value, source string) error {
+			temp, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s value: %s", source, value)
+			}
+			if temp < 0.0 || temp > 2.0 {
+				return fmt.Errorf("%s must be between 0.0 and 2.0, got: %f", source, temp)
+			}
+			c.Temperature = temp
+			return nil
+		},
+	},
+	{
+		name: "max_tokens", envSuffix: "MAX_TOKENS", flag: "max-tokens",
+		profile: func(p *Profile) (string, bool) { return strconv.Itoa(p.MaxTokens), p.MaxTokens > 0 },
+		current: func(c *AIConfig) string { return strconv.Itoa(c.MaxTokens) },
+		apply: func(c *AIConfig, value, source string) error {
+			tokens, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s value: %s", source, value)
+			}
+			limit := MaxTokensLimit(c.Provider)
+			if tokens <= 0 && limit == 0 {
+				return fmt.Errorf("%s must be positive, got: %d", source, tokens)
+			}
+			if tokens <= 0 || (limit > 0 && tokens > limit) {
+				return fmt.Errorf("%s must be between 1 and %d, got: %d", source, limit, tokens)
+			}
+			c.MaxTokens = tokens
+			return nil
+		},
+	},
+	{
+		name: "timeout", envSuffix: "TIMEOUT", flag: "timeout",
+		profile: func(p *Profile) (string, bool) { return strconv.Itoa(p.Timeout), p.Timeout > 0 },
+		current: func(c *AIConfig) string { return strconv.Itoa(c.Timeout) },
+		apply: func(c *AIConfig, value, source string) error {
+			timeout, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s value: %s", source, value)
+			}
+			if timeout <= 0 || timeout > 300 {
+				return fmt.Errorf("%s must be between 1 and 300 seconds, got: %d", source, timeout)
+			}
+			c.Timeout = timeout
+			return nil
+		},
+	},
+	{
+		name: "validation_retries", envSuffix: "VALIDATION_RETRIES", flag: "validation-retries",
+		profile: func(p *Profile) (string, bool) {
+			if p.ValidationRetries == nil {
+				return "", false
+			}
+			return strconv.Itoa(*p.ValidationRetries), true
+		},
+		current: func(c *AIConfig) string { return strconv.Itoa(c.ValidationRetries) },
+		apply: func(c *AIConfig, value, source string) error {
+			retries, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s value: %s", source, value)
+			}
+			if retries < 0 || retries > 5 {
+				return fmt.Errorf("%s must be between 0 and 5, got: %d", source, retries)
+			}
+			c.ValidationRetries = retries
+			return nil
+		},
+	},
+	{
+		name: "max_attempts", envSuffix: "MAX_ATTEMPTS",
+		profile: func(p *Profile) (string, bool) { return strconv.Itoa(p.MaxAttempts), p.MaxAttempts > 0 },
+		current: func(c *AIConfig) string { return strconv.Itoa(c.MaxAttempts) },
+		apply: func(c *AIConfig, value, source string) error {
+			attempts, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s value: %s", source, value)
+			}
+			if attempts < 1 || attempts > 10 {
+				return fmt.Errorf("%s must be between 1 and 10, got: %d", source, attempts)
+			}
+			c.MaxAttempts = attempts
+			return nil
+		},
+	},
+	{
+		name: "retry_deadline", envSuffix: "RETRY_DEADLINE",
+		profile: func(p *Profile) (string, bool) { return strconv.Itoa(p.RetryDeadline), p.RetryDeadline > 0 },
+		current: func(c *AIConfig) string { return strconv.Itoa(c.RetryDeadline) },
+		apply: func(c *AIConfig, value, source string) error {
+			deadline, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s value: %s", source, value)
+			}
+			if deadline <= 0 || deadline > 900 {
+				return fmt.Errorf("%s must be between 1 and 900 seconds, got: %d", source, deadline)
+			}
+			c.RetryDeadline = deadline
+			return nil
+		},
+	},
+	{
+		name: "deployment", envSuffix: "DEPLOYMENT", perProvider: true, only: []AIProviderType{ProviderAzure},
+		profile: func(p *Profile) (string, bool) { return p.Deployment, p.Deployment != "" },
+		current: func(c *AIConfig) string { return c.Deployment },
+		apply:   func(c *AIConfig, value, source string) error { c.Deployment = value; return nil },
+	},
+	{
+		name: "api_version", envSuffix: "API_VERSION", perProvider: true, only: []AIProviderType{ProviderAzure, ProviderAnthropic},
+		profile: func(p *Profile) (string, bool) { return p.APIVersion, p.APIVersion != "" },
+		current: func(c *AIConfig) string { return c.APIVersion },
+		apply:   func(c *AIConfig, value, source string) error { c.APIVersion = value; return nil },
+	},
+	{
+		name: "chat_path", envSuffix: "CHAT_PATH", perProvider: true, only: []AIProviderType{ProviderOpenAICompatible},
+		profile: func(p *Profile) (string, bool) { return p.ChatPath, p.ChatPath != "" },
+		current: func(c *AIConfig) string { return c.ChatPath },
+		apply:   func(c *AIConfig, value, source string) error { c.ChatPath = value; return nil },
+	},
+	{
+		name: "auth_header", envSuffix: "AUTH_HEADER", perProvider: true, only: []AIProviderType{ProviderOpenAICompatible},
+		profile: func(p *Profile) (string, bool) { return p.AuthHeader, p.AuthHeader != "" },
+		current: func(c *AIConfig) string { return c.AuthHeader },
+		apply:   func(c *AIConfig, value, source string) error { c.AuthHeader = value; return nil },
+	},
+	{
+		name: "auth_prefix", envSuffix: "AUTH_PREFIX", perProvider: true, only: []AIProviderType{ProviderOpenAICompatible},
+		profile: func(p *Profile) (string, bool) {
+			if p.AuthPrefix == nil {
+				return "", false
+			}
+			return *p.AuthPrefix, true
+		},
+		current: func(c *AIConfig) string { return c.AuthPrefix },
+		apply:   func(c *AIConfig, value, source string) error { c.AuthPrefix = value; return nil },
+	},
+	{
+		name: "headers", envSuffix: "HEADERS", perProvider: true, only: []AIProviderType{ProviderOpenAICompatible},
+		profile: func(p *Profile) (string, bool) { return formatHeaders(p.Headers), len(p.Headers) > 0 },
+		current: func(c *AIConfig) string { return formatHeaders(c.Headers) },
+		apply: func(c *AIConfig, value, source string) error {
+			headers, err := parseHeaders(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s value: %v", source, err)
+			}
+			c.Headers = headers
+			return nil
+		},
+	},
+	{
+		name: "extra_body", envSuffix: "EXTRA_BODY", perProvider: true, only: []AIProviderType{ProviderOpenAICompatible},
+		profile: func(p *Profile) (string, bool) {
+			if len(p.ExtraBody) == 0 {
+				return "", false
+			}
+			data, err := json.Marshal(jsonCompatible(p.ExtraBody))
+			if err != nil {
+				return "", false
+			}
+			return string(data), true
+		},
+		current: func(c *AIConfig) string {
+			if len(c.ExtraBody) == 0 {
+				return ""
+			}
+			data, _ := json.Marshal(c.ExtraBody)
+			return string(data)
+		},
+		apply: func(c *AIConfig, value, source string) error {
+			var extra map[string]interface{}
+			if err := json.Unmarshal([]byte(value), &extra); err != nil {
+				return fmt.Errorf("invalid %s value, expected a JSON object: %v", source, err)
+			}
+			c.ExtraBody = extra
+			return nil
+		},
+	},
+}
+
+// NewConfigResolver creates a resolver reading the process environment.
+// flags and profile may be nil.
+func NewConfigResolver(flags map[string]string, profileName string, profile *Profile) *ConfigResolver {
+	return &ConfigResolver{
+		Flags:       flags,
+		Profile:     profile,
+		ProfileName: profileName,
+		LookupEnv:   os.LookupEnv,
+	}
+}
+
+// Resolve builds the AI configuration and reports where each setting came
+// from. A missing API key is not an error here; see LoadConfig.
+func (r *ConfigResolver) Resolve() (*AIConfig, []ResolvedSetting, error) {
+	profile := r.Profile
+	if profile == nil {
+		profile = &Profile{}
+	}
+	var settings []ResolvedSetting
+
+	// Offline mode always uses the heuristic ranker
+	offline := ResolvedSetting{Name: "offline", Value: "false", Source: "default"}
+	if value, ok := r.Flags["offline"]; ok {
+		offline = ResolvedSetting{Name: "offline", Value: value, Source: "flag --offline"}
+	} else if value, ok := r.env("AI_OFFLINE"); ok {
+		offline = ResolvedSetting{Name: "offline", Value: value, Source: "env AI_OFFLINE"}
+	} else if profile.Offline {
+		offline = ResolvedSetting{Name: "offline", Value: "true", Source: r.profileSource()}
+	}
+	isOffline := IsTruthy(offline.Value)
+	offline.Value = strconv.FormatBool(isOffline)
+
+	// Determine provider
+	provider := ResolvedSetting{Name: "provider", Value: string(ProviderGroq), Source: "default"}
+	if value, ok := r.Flags["provider"]; ok {
+		provider = ResolvedSetting{Name: "provider", Value: value, Source: "flag --provider"}
+	} else if value, ok := r.env("AI_PROVIDER"); ok {
+		provider = ResolvedSetting{Name: "provider", Value: value, Source: "env AI_PROVIDER"}
+	} else if profile.Provider != "" {
+		provider = ResolvedSetting{Name: "provider", Value: profile.Provider, Source: r.profileSource()}
+	}
+	if isOffline {
+		provider = ResolvedSetting{Name: "provider", Value: string(ProviderHeuristic), Source: offline.Source}
+	}
+
+	chain, err := parseProviderChain(provider.Value)
+	if err != nil {
+		return nil, nil, err
+	}
+	provider.Value = joinProviderChain(chain)
+	settings = append(settings, provider, offline)
+
+	// Per-provider profile settings belong to the first provider of the profile
+	var profileProvider AIProviderType
+	if profile.Provider != "" {
+		if profileChain, err := parseProviderChain(profile.Provider); err == nil {
+			profileProvider = profileChain[0]
+		}
+	}
+
+	var config *AIConfig
+	for i, providerType := range chain {
+		primary := i == 0
+		profileApplies := profile.Provider == "" || (primary && profileProvider == providerType)
+		memberConfig, memberSettings, err := r.resolveProvider(providerType, profile, profileApplies, primary)
+		if err != nil {
+			return nil, nil, err
+		}
+		memberConfig.Offline = isOffline
+
+		if primary {
+			config = memberConfig
+		} else {
+			config.Fallbacks = append(config.Fallbacks, memberConfig)
+			for j := range memberSettings {
+				memberSettings[j].Name = string(providerType) + "." + memberSettings[j].Name
+			}
+		}
+		settings = append(settings, memberSettings...)
+	}
+
+	return config, settings, nil
+}
+
+// resolveProvider resolves the settings of one provider. Only the primary
+// provider of a chain takes per-provider settings from flags and generic env.
+func (r *ConfigResolver) resolveProvider(providerType AIProviderType, profile *Profile, profileApplies, primary bool) (*AIConfig, []ResolvedSetting, error) {
+	// Start with default config
+	config := DefaultConfigs[providerType]
+
+	var settings []ResolvedSetting
+	for _, spec := range providerSettings {
+		if !spec.appliesTo(providerType) {
+			continue
+		}
+
+		value, source, found := r.lookup(spec, providerType, profile, profileApplies, primary || !spec.perProvider)
+		if found {
+			if err := spec.apply(&config, value, source); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			source = "default"
+		}
+		settings = append(settings, ResolvedSetting{Name: spec.name, Value: spec.current(&config), Source: source})
+	}
+
+	return &config, settings, nil
+}
+
+// lookup finds the highest precedence value of a setting. Flags and generic
+// env are skipped unless shared is set.
+func (r *ConfigResolver) lookup(spec settingSpec, providerType AIProviderType, profile *Profile, profileApplies, shared bool) (string, string, bool) {
+	if spec.flag != "" && shared {
+		if value, ok := r.Flags[spec.name]; ok {
+			return value, "flag --" + spec.flag, true
+		}
+	}
+
+	for _, key := range providerEnvVars(providerType, spec.envSuffix) {
+		if value, ok := r.env(key); ok {
+			if key == "OLLAMA_HOST" {
+				value = ollamaBaseURL(value)
+			}
+			return value, "env " + key, true
+		}
+	}
+
+	if !shared {
+		return "", "", false
+	}
+
+	if value, ok := r.env("AI_" + spec.envSuffix); ok {
+		return value, "env AI_" + spec.envSuffix, true
+	}
+
+	if profileApplies || !spec.perProvider {
+		if value, ok := spec.profile(profile); ok {
+			return value, r.profileSource(), true
+		}
+	}
+
+	return "", "", false
+}
+
+// env returns a non-empty environment variable
+func (r *ConfigResolver) env(key string) (string, bool) {
+	lookup := r.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	value, ok := lookup(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", false
+	}
+	return value, true
+}
+
+func (r *ConfigResolver) profileSource() string {
+	if r.ProfileName == "" {
+		return "profile"
+	}
+	return "profile " + r.ProfileName
+}
+
+// joinProviderChain renders a chain the way AI_PROVIDER takes it
+func joinProviderChain(chain []AIProviderType) string {
+	names := make([]string, len(chain))
+	for i, providerType := range chain {
+		names[i] = string(providerType)
+	}
+	return strings.Join(names, ",")
+}
+
+// providerEnvVars returns the provider-prefixed variables for a setting,
+// most specific first
+func providerEnvVars(providerType AIProviderType, suffix string) []string {
+	prefix := strings.ToUpper(strings.ReplaceAll(string(providerType), "-", "_"))
+	vars := []string{prefix + "_" + suffix}
+
+	if alias, exists := envAliases[providerType][suffix]; exists {
+		vars = append(vars, alias)
+	}
+	return vars
+}
+
+// formatHeaders renders headers as Name=value pairs, sorted by name
+func formatHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + headers[name]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// parseHeaders parses comma-separated Name=value pairs
+func parseHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, headerValue, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("expected Name=value, got %q", pair)
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(headerValue)
+	}
+	return headers, nil
+}
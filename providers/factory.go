@@ -2,14 +2,20 @@ package providers
 
 import (
 	"fmt"
+	"strings"
 )
 
 // AIProviderType represents the type of AI provider
 type AIProviderType string
 
 const (
-	ProviderGroq   AIProviderType = "groq"
-	ProviderOpenAI AIProviderType = "openai"
+	ProviderGroq             AIProviderType = "groq"
+	ProviderOpenAI           AIProviderType = "openai"
+	ProviderHeuristic        AIProviderType = "heuristic"
+	ProviderOllama           AIProviderType = "ollama"
+	ProviderOpenAICompatible AIProviderType = "openai-compatible"
+	ProviderAzure            AIProviderType = "azure"
+	ProviderAnthropic        AIProviderType = "anthropic"
 )
 
 // AIFactory creates AI providers based on configuration
@@ -20,23 +26,85 @@ func NewAIFactory() *AIFactory {
 	return &AIFactory{}
 }
 
-// CreateProvider creates an AI provider based on the specified type and configuration
+// CreateProvider creates an AI provider based on the specified type and
+// configuration. A configuration with fallbacks creates a ChainProvider.
 func (f *AIFactory) CreateProvider(config *AIConfig) (AIProvider, error) {
+	if len(config.Fallbacks) > 0 {
+		return f.createChain(config)
+	}
+
 	// Validate configuration
 	if err := ValidateConfig(config); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %v", err)
 	}
 
+	// Offline mode guarantees that no provider can open a network connection
+	if config.Offline && !IsOfflineProvider(config.Provider) {
+		return nil, fmt.Errorf("provider %s needs network access and cannot be used in offline mode", config.Provider)
+	}
+
+	provider, err := newProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only answers that match a candidate are passed on
+	return NewValidatingProvider(provider, config.ValidationRetries), nil
+}
+
+// newProvider creates the provider implementation for config.Provider
+func newProvider(config *AIConfig) (AIProvider, error) {
 	switch config.Provider {
+	case ProviderHeuristic:
+		return NewHeuristicProvider(config), nil
 	case ProviderGroq:
 		return NewGroqProvider(config), nil
-	case ProviderOpenAI:
+	case ProviderOpenAI, ProviderAzure:
 		return NewOpenAIProvider(config), nil
+	case ProviderAnthropic:
+		return NewAnthropicProvider(config), nil
+	case ProviderOllama:
+		return NewOllamaProvider(config), nil
+	case ProviderOpenAICompatible:
+		return NewOpenAICompatibleProvider(config), nil
 	default:
 		return nil, fmt.Errorf("unsupported AI provider: %s", config.Provider)
 	}
 }
 
+// createChain creates the providers of a chain in order. A provider that
+// cannot be created, e.g. for a missing API key, is skipped at analysis time
+// with the reason; the chain fails only when no provider is usable.
+func (f *AIFactory) createChain(config *AIConfig) (AIProvider, error) {
+	primary := *config
+	primary.Fallbacks = nil
+	configs := append([]*AIConfig{&primary}, config.Fallbacks...)
+
+	chain := &ChainProvider{}
+	var reasons []string
+	for _, memberConfig := range configs {
+		provider, err := f.CreateProvider(memberConfig)
+		if err != nil {
+			if memberConfig.APIKey == "" && RequiresAPIKey(memberConfig.Provider) {
+				err = fmt.Errorf("%s is not set", GetProviderSpecificEnvVars(memberConfig.Provider)["API_KEY"])
+			}
+			name := string(memberConfig.Provider)
+			if unusable, nameErr := newProvider(memberConfig); nameErr == nil {
+				name = unusable.GetProviderName()
+			}
+			chain.members = append(chain.members, chainMember{name: name, model: memberConfig.Model, err: err})
+			reasons = append(reasons, fmt.Sprintf("%s: %v", memberConfig.Provider, err))
+			continue
+		}
+		chain.members = append(chain.members, chainMember{name: provider.GetProviderName(), model: memberConfig.Model, provider: provider})
+	}
+
+	if len(reasons) == len(configs) {
+		return nil, fmt.Errorf("no usable provider in chain (%s)", strings.Join(reasons, "; "))
+	}
+	return chain, nil
+}
+
 // CreateProviderFromEnv creates an AI provider from environment variables
 func (f *AIFactory) CreateProviderFromEnv() (AIProvider, error) {
 	// Load configuration from environment
@@ -51,7 +119,7 @@ func (f *AIFactory) CreateProviderFromEnv() (AIProvider, error) {
 
 // CreateProviderWithDefaults creates an AI provider with default configuration
 func (f *AIFactory) CreateProviderWithDefaults(providerType AIProviderType, apiKey string) (AIProvider, error) {
-	if apiKey == "" {
+	if apiKey == "" && RequiresAPIKey(providerType) {
 		return nil, fmt.Errorf("API key is required")
 	}
 
@@ -64,7 +132,7 @@ func (f *AIFactory) CreateProviderWithDefaults(providerType AIProviderType, apiK
 
 // GetSupportedProviders returns a list of supported AI providers
 func (f *AIFactory) GetSupportedProviders() []AIProviderType {
-	return []AIProviderType{ProviderGroq, ProviderOpenAI}
+	return []AIProviderType{ProviderGroq, ProviderOpenAI, ProviderAzure, ProviderAnthropic, ProviderOpenAICompatible, ProviderOllama, ProviderHeuristic}
 }
 
 // GetDefaultConfig returns the default configuration for a provider
@@ -75,3 +143,19 @@ func (f *AIFactory) GetDefaultConfig(providerType AIProviderType) (AIConfig, err
 	}
 	return config, nil
 }
+
+// RequiresAPIKey reports whether a provider needs an API key
+func RequiresAPIKey(providerType AIProviderType) bool {
+	switch providerType {
+	case ProviderHeuristic, ProviderOllama, ProviderOpenAICompatible:
+		// Gateways may authenticate with headers only
+		return false
+	default:
+		return true
+	}
+}
+
+// IsOfflineProvider reports whether a provider works without any network access
+func IsOfflineProvider(providerType AIProviderType) bool {
+	return providerType == ProviderHeuristic
+}
@@ -2,11 +2,11 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -22,6 +22,13 @@ type GroqRequest struct {
 	Messages    []Message `json:"messages"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Temperature float64   `json:"temperature,omitempty"`
+
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat selects JSON mode on OpenAI-compatible chat APIs
+type ResponseFormat struct {
+	Type string `json:"type"`
 }
 
 // Message represents a chat message
@@ -32,7 +39,15 @@ type Message struct {
 
 // GroqResponse represents the response from Groq API
 type GroqResponse struct {
-	Choices []Choice `json:"choices"`
+	Choices []Choice  `json:"choices"`
+	Usage   ChatUsage `json:"usage"`
+}
+
+// ChatUsage is the token count of a chat completion
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // Choice represents a choice in the response
@@ -44,9 +59,7 @@ type Choice struct {
 func NewGroqProvider(config *AIConfig) *GroqProvider {
 	return &GroqProvider{
 		config: config,
-		client: &http.Client{
-			Timeout: time.Duration(config.Timeout) * time.Second,
-		},
+		client: newHTTPClient(config),
 	}
 }
 
@@ -61,7 +74,7 @@ func (g *GroqProvider) UpdateConfig(config *AIConfig) error {
 		return err
 	}
 	g.config = config
-	g.client.Timeout = time.Duration(config.Timeout) * time.Second
+	g.client = newHTTPClient(config)
 	return nil
 }
 
@@ -82,78 +95,100 @@ func (g *GroqProvider) SetMaxTokens(tokens int) {
 
 // AnalyzeVersions implements the AIProvider interface
 func (g *GroqProvider) AnalyzeVersions(binaryName string, candidates []string) (string, error) {
-	if len(candidates) == 0 {
-		return "", fmt.Errorf("no version candidates provided")
+	return g.AnalyzeVersionsContext(context.Background(), binaryName, candidates)
+}
+
+// AnalyzeVersionsContext implements the AIProvider interface
+func (g *GroqProvider) AnalyzeVersionsContext(ctx context.Context, binaryName string, candidates []string) (string, error) {
+	resp, err := g.AnalyzeRequestContext(ctx, &AIRequest{
+		BinaryName: binaryName,
+		Candidates: candidates,
+	})
+	if err != nil {
+		return "", err
 	}
+	return resp.Version, nil
+}
 
-	prompt := g.buildPrompt(binaryName, candidates)
+// AnalyzeRequest implements the AIProvider interface
+func (g *GroqProvider) AnalyzeRequest(request *AIRequest) (*AIResponse, error) {
+	return g.AnalyzeRequestContext(context.Background(), request)
+}
+
+// AnalyzeRequestContext implements the AIProvider interface. The model is asked for
+// a JSON object in JSON mode.
+func (g *GroqProvider) AnalyzeRequestContext(ctx context.Context, request *AIRequest) (*AIResponse, error) {
+	if len(request.Candidates) == 0 {
+		return nil, fmt.Errorf("no version candidates provided")
+	}
 
 	reqBody := GroqRequest{
 		Model: g.config.Model,
 		Messages: []Message{
 			{
 				Role:    "system",
-				Content: "You are a version number analyzer. Your task is to identify the most likely semantic version from a list of candidates. Respond with only the version number, nothing else.",
+				Content: analysisSystemPrompt,
 			},
 			{
 				Role:    "user",
-				Content: prompt,
+				Content: buildAnalysisPrompt(request),
 			},
 		},
-		MaxTokens:   g.config.MaxTokens,
-		Temperature: g.config.Temperature,
+		MaxTokens:      g.config.MaxTokens,
+		Temperature:    g.config.Temperature,
+		ResponseFormat: &ResponseFormat{Type: "json_object"},
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("error marshaling request: %v", err)
+		return nil, fmt.Errorf("error marshaling request: %v", err)
 	}
 
 	// Use base URL from config
 	url := fmt.Sprintf("%s/chat/completions", g.config.BaseURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %v", err)
+		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+g.config.APIKey)
 
+	meter := UsageMeterFromContext(ctx)
+	if err := meter.Allow(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
 	resp, err := g.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("error making request: %v", err)
+		return nil, fmt.Errorf("error making request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var groqResp GroqResponse
 	if err := json.NewDecoder(resp.Body).Decode(&groqResp); err != nil {
-		return "", fmt.Errorf("error decoding response: %v", err)
+		return nil, fmt.Errorf("error decoding response: %v", err)
 	}
+	usage := meter.Record(g.config, groqResp.Usage.PromptTokens, groqResp.Usage.CompletionTokens, time.Since(start))
 
 	if len(groqResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from Groq API")
+		return nil, fmt.Errorf("no response from Groq API")
 	}
 
-	version := strings.TrimSpace(groqResp.Choices[0].Message.Content)
-	return version, nil
+	aiResp, err := parseAIResponse(groqResp.Choices[0].Message.Content, g.GetProviderName())
+	if err != nil {
+		return nil, err
+	}
+	aiResp.Usage = &usage
+	return aiResp, nil
 }
 
 // GetProviderName returns the name of the provider
 func (g *GroqProvider) GetProviderName() string {
 	return "Groq"
 }
-
-// buildPrompt creates the prompt for version analysis
-func (g *GroqProvider) buildPrompt(binaryName string, candidates []string) string {
-	return fmt.Sprintf(`Given the following candidate strings, identify the most likely semantic version for the %s binary. Ignore unrelated floats or library dependencies.
-
-Candidates:
-%s
-
-Please provide only the most likely version number in your response, nothing else.`, binaryName, "- "+strings.Join(candidates, "\n- "))
-}
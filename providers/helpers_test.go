@@ -0,0 +1,123 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newStandIn starts an httptest server that passes POST requests for path to
+// handler and answers anything else with 404. It returns the server's URL.
+func newStandIn(t *testing.T, path string, handler http.HandlerFunc) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// newOllamaStandIn starts an httptest server that answers /api/chat with handler
+func newOllamaStandIn(t *testing.T, handler func(w http.ResponseWriter, req OllamaRequest)) *AIConfig {
+	t.Helper()
+
+	url := newStandIn(t, "/api/chat", func(w http.ResponseWriter, r *http.Request) {
+		var req OllamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		handler(w, req)
+	})
+
+	config := DefaultConfigs[ProviderOllama]
+	config.BaseURL = url
+	return &config
+}
+
+// newAnthropicStandIn starts an httptest server for /v1/messages that checks
+// the request and answers with status and body
+func newAnthropicStandIn(t *testing.T, status int, body string, check func(r *http.Request, req AnthropicRequest)) *AIConfig {
+	t.Helper()
+
+	url := newStandIn(t, "/v1/messages", func(w http.ResponseWriter, r *http.Request) {
+		var req AnthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if check != nil {
+			check(r, req)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+
+	config := DefaultConfigs[ProviderAnthropic]
+	config.BaseURL = url
+	config.APIKey = "sk-ant-test"
+	return &config
+}
+
+// llamaCppResponse is a chat completion as returned by the llama.cpp server,
+// which adds its own fields next to the OpenAI ones
+const llamaCppResponse = `{
+  "choices": [{
+    "finish_reason": "stop",
+    "index": 0,
+    "message": {"role": "assistant", "content": "{\"version\": \"2.39.5\", \"confidence\": 0.8, \"product\": \"git\", \"rationale\": \"Matches the git version string\"}"}
+  }],
+  "created": 1718000000,
+  "model": "qwen2.5-7b-instruct-q4_k_m.gguf",
+  "object": "chat.completion",
+  "usage": {"completion_tokens": 30, "prompt_tokens": 180, "total_tokens": 210},
+  "timings": {"prompt_n": 180, "predicted_n": 30}
+}`
+
+// newLlamaCppStandIn starts an httptest server that mimics llama.cpp's
+// /v1/chat/completions endpoint and records the last request body
+func newLlamaCppStandIn(t *testing.T, check func(r *http.Request)) (*AIConfig, *map[string]interface{}) {
+	t.Helper()
+
+	var body map[string]interface{}
+	url := newStandIn(t, "/gateway/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		check(r)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(llamaCppResponse))
+	})
+
+	config := DefaultConfigs[ProviderOpenAICompatible]
+	config.BaseURL = url + "/gateway"
+	return &config, &body
+}
+
+// newRetryStandIn starts an httptest server that hands each request and its
+// 1-based number to handler. It returns a request to the server, a
+// retryTransport with policy and the number of requests served so far.
+func newRetryStandIn(t *testing.T, policy RetryPolicy, handler func(w http.ResponseWriter, n int32)) (*http.Request, *retryTransport, *int32) {
+	t.Helper()
+
+	var calls int32
+	url := newStandIn(t, "/", func(w http.ResponseWriter, r *http.Request) {
+		handler(w, atomic.AddInt32(&calls, 1))
+	})
+
+	req, err := http.NewRequest(http.MethodPost, url+"/", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	return req, &retryTransport{base: http.DefaultTransport, policy: policy}, &calls
+}
@@ -0,0 +1,277 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// penalizedPatterns lists patterns that describe the toolchain or linked
+// libraries rather than the binary itself
+var penalizedPatterns = map[string]bool{
+	"GLIBC Version":         true,
+	"GLIBC Context Version": true,
+	"Compiler Version":      true,
+	"Library Version":       true,
+}
+
+// temporalPatterns lists patterns that find dates and years, with the score
+// each loses since a date is rarely the version a user asks for
+var temporalPatterns = map[string]float64{
+	"Copyright Year Version": 40,
+	"Date-based Version":     30,
+}
+
+const (
+	// lowPriority is the pattern priority from which a match loses lowPriorityPenalty
+	lowPriority        = 5
+	lowPriorityPenalty = 15.0
+	// minHeuristicScore is the score the best candidate needs before it is
+	// reported, below it the version is unknown
+	minHeuristicScore = 50.0
+)
+
+// dependencyContext matches context strings that point at a dependency even
+// when the pattern that found the version was a generic one
+var dependencyContext = regexp.MustCompile(`(?i)\b(?:glibc|gcc|clang|libc|libstdc\+\+)\b|\blib\w+\.so\b`)
+
+// goPseudoVersion matches Go pseudo-versions left behind by dependencies
+var goPseudoVersion = regexp.MustCompile(`^\d+\.\d+\.\d+-(?:\d+\.)?\d{14}`)
+
+// nameMajorVersion extracts a trailing major version from a binary name
+var nameMajorVersion = regexp.MustCompile(`[a-z][-_]?(\d+)$`)
+
+// HeuristicProvider ranks candidates deterministically from scan evidence.
+// It needs no API key and never makes network calls.
+type HeuristicProvider struct {
+	config *AIConfig
+}
+
+// NewHeuristicProvider creates a new offline heuristic provider
+func NewHeuristicProvider(config *AIConfig) *HeuristicProvider {
+	return &HeuristicProvider{
+		config: config,
+	}
+}
+
+// GetConfig returns the current configuration
+func (h *HeuristicProvider) GetConfig() *AIConfig {
+	return h.config
+}
+
+// AnalyzeVersions implements the AIProvider interface
+func (h *HeuristicProvider) AnalyzeVersions(binaryName string, candidates []string) (string, error) {
+	return h.AnalyzeVersionsContext(context.Background(), binaryName, candidates)
+}
+
+// AnalyzeVersionsContext implements the AIProvider interface
+func (h *HeuristicProvider) AnalyzeVersionsContext(ctx context.Context, binaryName string, candidates []string) (string, error) {
+	resp, err := h.AnalyzeRequestContext(ctx, &AIRequest{
+		BinaryName: binaryName,
+		Candidates: candidates,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Version, nil
+}
+
+// AnalyzeRequest implements the AIProvider interface
+func (h *HeuristicProvider) AnalyzeRequest(req *AIRequest) (*AIResponse, error) {
+	return h.AnalyzeRequestContext(context.Background(), req)
+}
+
+// AnalyzeRequestContext implements the AIProvider interface. Candidates without
+// evidence are ranked from the version string alone.
+func (h *HeuristicProvider) AnalyzeRequestContext(ctx context.Context, req *AIRequest) (*AIResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	evidence := req.Evidence
+	if len(evidence) == 0 {
+		for _, candidate := range req.Candidates {
+			evidence = append(evidence, CandidateEvidence{Version: candidate})
+		}
+	}
+
+	if len(evidence) == 0 {
+		return nil, fmt.Errorf("no version candidates provided")
+	}
+
+	scores := make([]float64, len(evidence))
+	bestIndex := 0
+	for i, candidate := range evidence {
+		scores[i] = h.score(req.BinaryName, candidate, i, len(evidence))
+
+		// Ties keep scan order, which already favours the string sections
+		if scores[i] > scores[bestIndex] {
+			bestIndex = i
+		}
+	}
+
+	secondScore := math.Inf(-1)
+	for i, score := range scores {
+		if i != bestIndex && score > secondScore {
+			secondScore = score
+		}
+	}
+
+	// When even the best candidate names a toolchain or library, none of them
+	// is the binary's own version
+	if best := evidence[bestIndex]; isDependencyEvidence(best) {
+		return &AIResponse{
+			Version:      UnknownVersion,
+			Rationale:    fmt.Sprintf("All %d candidates look like toolchain or dependency versions, the best was %s from %s", len(evidence), best.Version, evidenceSource(best)),
+			ProviderName: h.GetProviderName(),
+		}, nil
+	}
+	if scores[bestIndex] < minHeuristicScore {
+		return &AIResponse{
+			Version:      UnknownVersion,
+			Rationale:    fmt.Sprintf("None of %d candidates is convincing, the best was %s scoring %.0f", len(evidence), evidence[bestIndex].Version, scores[bestIndex]),
+			ProviderName: h.GetProviderName(),
+		}, nil
+	}
+
+	return &AIResponse{
+		Version:      evidence[bestIndex].Version,
+		Confidence:   heuristicConfidence(scores[bestIndex], secondScore),
+		Rationale:    heuristicRationale(evidence[bestIndex], len(evidence)),
+		ProviderName: h.GetProviderName(),
+	}, nil
+}
+
+// isDependencyEvidence reports whether a candidate was found by a toolchain or
+// library pattern, or next to the name of one
+func isDependencyEvidence(candidate CandidateEvidence) bool {
+	return penalizedPatterns[candidate.Pattern] || dependencyContext.MatchString(candidate.Context)
+}
+
+// evidenceSource names the pattern or context a candidate came from
+func evidenceSource(candidate CandidateEvidence) string {
+	if penalizedPatterns[candidate.Pattern] {
+		return candidate.Pattern
+	}
+	return fmt.Sprintf("%q", candidate.Context)
+}
+
+// heuristicRationale describes the evidence behind the chosen candidate
+func heuristicRationale(candidate CandidateEvidence, total int) string {
+	if candidate.Pattern == "" {
+		return fmt.Sprintf("Highest scoring of %d candidates", total)
+	}
+	return fmt.Sprintf("Highest scoring of %d candidates, matched by %s and seen %d time(s)", total, candidate.Pattern, candidate.Count)
+}
+
+// score rates how likely a candidate is the binary's own version. index and
+// total give the candidate's position in scan order.
+func (h *HeuristicProvider) score(binaryName string, candidate CandidateEvidence, index, total int) float64 {
+	score := 0.0
+
+	// Pattern priority: 1 is the most reliable, unknown sits in the middle
+	priority := candidate.Priority
+	if priority <= 0 {
+		priority = 5
+	}
+	score += float64(10-priority) * 10
+
+	// Frequency, on a log scale so a repeated table entry cannot dominate
+	if candidate.Count > 0 {
+		score += math.Log2(float64(candidate.Count)+1) * 3
+	}
+
+	// Scan order, since the sections most likely to hold the version are scanned first
+	score += 10 * (1 - float64(index)/float64(total))
+
+	// Closeness to the binary name in the surrounding text
+	score += nameProximity(binaryName, candidate)
+
+	// Toolchain and dependency versions are rarely the answer
+	if penalizedPatterns[candidate.Pattern] {
+		score -= 50
+	} else if dependencyContext.MatchString(candidate.Context) {
+		score -= 30
+	}
+	score -= temporalPatterns[candidate.Pattern]
+	if candidate.Priority >= lowPriority {
+		score -= lowPriorityPenalty
+	}
+
+	// A string holding nothing but the version is usually a version constant
+	if candidate.Context == candidate.Version {
+		score += 10
+	}
+
+	// Shape of the version itself
+	switch {
+	case goPseudoVersion.MatchString(candidate.Version):
+		score -= 20
+	case strings.Count(candidate.Version, ".") == 2:
+		score += 5
+	}
+
+	return score
+}
+
+// nameProximity rewards candidates whose context mentions the binary name,
+// the closer to the version the better
+func nameProximity(binaryName string, candidate CandidateEvidence) float64 {
+	name := strings.ToLower(strings.TrimSuffix(binaryName, filepath.Ext(binaryName)))
+
+	// Names like python3 or gcc-12 carry the major version
+	if match := nameMajorVersion.FindStringSubmatch(name); match != nil {
+		if strings.HasPrefix(candidate.Version, match[1]+".") {
+			return 15
+		}
+	}
+
+	context := strings.ToLower(candidate.Context)
+	if name == "" || context == "" {
+		return 0
+	}
+
+	nameIndex := strings.Index(context, name)
+	versionIndex := strings.Index(context, strings.ToLower(candidate.Version))
+	if nameIndex < 0 {
+		return 0
+	}
+	if versionIndex < 0 {
+		return 10
+	}
+
+	distance := versionIndex - (nameIndex + len(name))
+	if distance < 0 {
+		distance = -distance
+	}
+
+	bonus := 30 - float64(distance)/2
+	if bonus < 10 {
+		bonus = 10
+	}
+	return bonus
+}
+
+// heuristicConfidence turns the best score into a 0-1 confidence, lowered when
+// the runner-up scored close to it. second is -Inf for a lone candidate.
+func heuristicConfidence(best, second float64) float64 {
+	confidence := 0.4 + (best-minHeuristicScore)/100
+	if margin := 0.5 + (best-second)/40; margin < 1 {
+		confidence *= margin
+	}
+	if confidence < 0.1 {
+		confidence = 0.1
+	}
+	if confidence > 0.95 {
+		confidence = 0.95
+	}
+	return confidence
+}
+
+// GetProviderName returns the name of the provider
+func (h *HeuristicProvider) GetProviderName() string {
+	return "Heuristic"
+}
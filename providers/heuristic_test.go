@@ -0,0 +1,157 @@
+package providers
+
+import (
+	"testing"
+)
+
+func TestHeuristicPrefersOwnVersion(t *testing.T) {
+	resp, err := NewHeuristicProvider(&AIConfig{}).AnalyzeRequest(&AIRequest{
+		BinaryName: "curl",
+		Candidates: []string{"2.34", "7.88.1"},
+		Evidence: []CandidateEvidence{
+			{Version: "2.34", Pattern: "GLIBC Version", Priority: 3, Count: 1, Context: "GLIBC_2.34"},
+			{Version: "7.88.1", Pattern: "Semantic Version", Priority: 2, Count: 2, Context: "curl 7.88.1 (x86_64-pc-linux-gnu)"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeRequest: %v", err)
+	}
+	if resp.Version != "7.88.1" {
+		t.Errorf("version = %q, want 7.88.1", resp.Version)
+	}
+}
+
+func TestHeuristicOnlyDependencyVersions(t *testing.T) {
+	req := &AIRequest{
+		BinaryName: "ls",
+		Candidates: []string{"1.0", "2.34", "2.2.5"},
+		Evidence: []CandidateEvidence{
+			{Version: "1.0", Pattern: "Library Version", Priority: 3, Count: 1, Context: "LIBSELINUX_1.0"},
+			{Version: "2.34", Pattern: "GLIBC Version", Priority: 3, Count: 1, Context: "GLIBC_2.34"},
+			{Version: "2.2.5", Pattern: "GLIBC Version", Priority: 3, Count: 1, Context: "GLIBC_2.2.5"},
+		},
+	}
+
+	resp, err := NewHeuristicProvider(&AIConfig{}).AnalyzeRequest(req)
+	if err != nil {
+		t.Fatalf("AnalyzeRequest: %v", err)
+	}
+	if resp.Version != UnknownVersion {
+		t.Errorf("version = %q, want %q", resp.Version, UnknownVersion)
+	}
+	if resp.Confidence != 0 {
+		t.Errorf("confidence = %v, want 0 for an unknown version", resp.Confidence)
+	}
+
+	// The validation layer passes the unknown on instead of re-prompting
+	validated, err := NewValidatingProvider(NewHeuristicProvider(&AIConfig{}), DefaultValidationRetries).AnalyzeRequest(req)
+	if err != nil {
+		t.Fatalf("validated AnalyzeRequest: %v", err)
+	}
+	if validated.Version != UnknownVersion {
+		t.Errorf("validated version = %q, want %q", validated.Version, UnknownVersion)
+	}
+}
+
+func TestHeuristicDependencyContext(t *testing.T) {
+	// A generic pattern that matched the compiler banner still names the toolchain
+	resp, err := NewHeuristicProvider(&AIConfig{}).AnalyzeRequest(&AIRequest{
+		BinaryName: "hello",
+		Candidates: []string{"12.2.0"},
+		Evidence: []CandidateEvidence{
+			{Version: "12.2.0", Pattern: "Semantic Version", Priority: 2, Count: 1, Context: "GCC: (Debian 12.2.0-14) 12.2.0"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeRequest: %v", err)
+	}
+	if resp.Version != UnknownVersion {
+		t.Errorf("version = %q, want %q", resp.Version, UnknownVersion)
+	}
+}
+
+func TestHeuristicTemporalPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		evidence []CandidateEvidence
+	}{
+		{
+			name: "copyright year",
+			evidence: []CandidateEvidence{
+				{Version: "2019.1", Pattern: "Copyright Year Version", Priority: 8, Count: 1, Context: "Copyright (C) 2019.1 Example Corp"},
+			},
+		},
+		{
+			name: "build date",
+			evidence: []CandidateEvidence{
+				{Version: "2023.12.15", Pattern: "Date-based Version", Priority: 6, Count: 1, Context: "built 2023.12.15"},
+			},
+		},
+		{
+			name: "low priority only",
+			evidence: []CandidateEvidence{
+				{Version: "2023.12.15", Pattern: "Date-based Version", Priority: 6, Count: 1, Context: "built 2023.12.15"},
+				{Version: "3.1", Pattern: "API Version", Priority: 5, Count: 1, Context: "api 3.1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var candidates []string
+			for _, e := range tt.evidence {
+				candidates = append(candidates, e.Version)
+			}
+			resp, err := NewHeuristicProvider(&AIConfig{}).AnalyzeRequest(&AIRequest{
+				BinaryName: "tool",
+				Candidates: candidates,
+				Evidence:   tt.evidence,
+			})
+			if err != nil {
+				t.Fatalf("AnalyzeRequest: %v", err)
+			}
+			if resp.Version != UnknownVersion {
+				t.Errorf("version = %q, want %q", resp.Version, UnknownVersion)
+			}
+		})
+	}
+}
+
+func TestHeuristicConfidenceFollowsScore(t *testing.T) {
+	analyze := func(evidence ...CandidateEvidence) *AIResponse {
+		t.Helper()
+		var candidates []string
+		for _, e := range evidence {
+			candidates = append(candidates, e.Version)
+		}
+		resp, err := NewHeuristicProvider(&AIConfig{}).AnalyzeRequest(&AIRequest{
+			BinaryName: "curl",
+			Candidates: candidates,
+			Evidence:   evidence,
+		})
+		if err != nil {
+			t.Fatalf("AnalyzeRequest: %v", err)
+		}
+		return resp
+	}
+
+	strong := analyze(CandidateEvidence{Version: "7.88.1", Pattern: "Standard Version Declaration", Priority: 1, Count: 3, Context: "curl 7.88.1"})
+	weak := analyze(CandidateEvidence{Version: "7.88", Pattern: "Package Version", Priority: 4, Count: 1, Context: "pkg 7.88"})
+	if strong.Version != "7.88.1" || weak.Version != "7.88" {
+		t.Fatalf("versions = %q, %q, want 7.88.1, 7.88", strong.Version, weak.Version)
+	}
+	if weak.Confidence >= strong.Confidence {
+		t.Errorf("weak lone candidate confidence %v, want below strong lone candidate %v", weak.Confidence, strong.Confidence)
+	}
+	if weak.Confidence >= 0.9 {
+		t.Errorf("weak lone candidate confidence = %v, want it derived from the score rather than a fixed 0.9", weak.Confidence)
+	}
+
+	close := analyze(
+		CandidateEvidence{Version: "7.88.1", Pattern: "Standard Version Declaration", Priority: 1, Count: 3, Context: "curl 7.88.1"},
+		CandidateEvidence{Version: "7.87.0", Pattern: "Standard Version Declaration", Priority: 1, Count: 3, Context: "curl 7.87.0"},
+	)
+	if close.Confidence >= strong.Confidence {
+		t.Errorf("contested confidence %v, want below uncontested %v", close.Confidence, strong.Confidence)
+	}
+}
@@ -1,22 +1,49 @@
 package providers
 
-// AIProvider defines the interface for AI providers
+import "context"
+
+// AIProvider defines the interface for AI providers. The Context variants
+// stop in-flight requests when ctx is cancelled or its deadline passes; the
+// others use context.Background().
 type AIProvider interface {
 	AnalyzeVersions(binaryName string, candidates []string) (string, error)
+	AnalyzeVersionsContext(ctx context.Context, binaryName string, candidates []string) (string, error)
+	AnalyzeRequest(req *AIRequest) (*AIResponse, error)
+	AnalyzeRequestContext(ctx context.Context, req *AIRequest) (*AIResponse, error)
 	GetProviderName() string
 }
 
 // AIRequest represents a common request structure for AI analysis
 type AIRequest struct {
-	BinaryName  string   `json:"binary_name"`
-	Candidates  []string `json:"candidates"`
-	Temperature float64  `json:"temperature,omitempty"`
-	MaxTokens   int      `json:"max_tokens,omitempty"`
+	BinaryName  string              `json:"binary_name"`
+	Candidates  []string            `json:"candidates"`
+	Evidence    []CandidateEvidence `json:"evidence,omitempty"`
+	Temperature float64             `json:"temperature,omitempty"`
+	MaxTokens   int                 `json:"max_tokens,omitempty"`
+	Feedback    string              `json:"feedback,omitempty"` // Why the previous answer was rejected
+
+	// SHA-256 of the binary, which lets a CachingProvider reuse earlier verdicts
+	BinarySHA256 string `json:"binary_sha256,omitempty"`
+}
+
+// CandidateEvidence describes where and how a version candidate was found
+type CandidateEvidence struct {
+	Version  string `json:"version"`
+	Pattern  string `json:"pattern,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	Count    int    `json:"count,omitempty"`
+	Context  string `json:"context,omitempty"`
 }
 
 // AIResponse represents a common response structure from AI providers
 type AIResponse struct {
-	Version      string  `json:"version"`
-	Confidence   float64 `json:"confidence,omitempty"`
-	ProviderName string  `json:"provider_name"`
+	Version      string            `json:"version"`
+	Confidence   float64           `json:"confidence,omitempty"` // 0-1, zero when the provider gave none
+	Product      string            `json:"product,omitempty"`
+	Rationale    string            `json:"rationale,omitempty"`
+	ProviderName string            `json:"provider_name"`
+	Model        string            `json:"model,omitempty"`   // Set by a chain to the answering provider's model
+	Skipped      []SkippedProvider `json:"skipped,omitempty"` // Chain providers that failed before this answer
+	Cached       bool              `json:"cached,omitempty"`  // Served from the verdict cache
+	Usage        *Usage            `json:"usage,omitempty"`   // Tokens, latency and cost of the calls behind this answer
 }
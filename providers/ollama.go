@@ -0,0 +1,199 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OllamaProvider implements the AIProvider interface for a local Ollama daemon
+type OllamaProvider struct {
+	config *AIConfig
+	client *http.Client
+}
+
+// OllamaRequest represents the request structure for the Ollama chat API
+type OllamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   string        `json:"format,omitempty"`
+	Options  OllamaOptions `json:"options"`
+}
+
+// OllamaOptions holds the model parameters of an Ollama request
+type OllamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+// OllamaResponse represents a non-streaming response from the Ollama chat API
+type OllamaResponse struct {
+	Model   string  `json:"model"`
+	Message Message `json:"message"`
+	Done    bool    `json:"done"`
+	Error   string  `json:"error,omitempty"`
+
+	PromptEvalCount int `json:"prompt_eval_count"` // Prompt tokens
+	EvalCount       int `json:"eval_count"`        // Generated tokens
+}
+
+// OllamaModelError is returned when the requested model has not been pulled
+// into the Ollama daemon
+type OllamaModelError struct {
+	Model   string
+	Message string
+}
+
+func (e *OllamaModelError) Error() string {
+	return fmt.Sprintf("model %s is not available in Ollama (%s), run 'ollama pull %s' first", e.Model, e.Message, e.Model)
+}
+
+// NewOllamaProvider creates a new Ollama provider with configuration
+func NewOllamaProvider(config *AIConfig) *OllamaProvider {
+	return &OllamaProvider{
+		config: config,
+		client: newHTTPClient(config),
+	}
+}
+
+// GetConfig returns the current configuration
+func (o *OllamaProvider) GetConfig() *AIConfig {
+	return o.config
+}
+
+// UpdateConfig updates the provider configuration
+func (o *OllamaProvider) UpdateConfig(config *AIConfig) error {
+	if err := ValidateConfig(config); err != nil {
+		return err
+	}
+	o.config = config
+	o.client = newHTTPClient(config)
+	return nil
+}
+
+// SetModel allows changing the model used by Ollama
+func (o *OllamaProvider) SetModel(model string) {
+	o.config.Model = model
+}
+
+// AnalyzeVersions implements the AIProvider interface
+func (o *OllamaProvider) AnalyzeVersions(binaryName string, candidates []string) (string, error) {
+	return o.AnalyzeVersionsContext(context.Background(), binaryName, candidates)
+}
+
+// AnalyzeVersionsContext implements the AIProvider interface
+func (o *OllamaProvider) AnalyzeVersionsContext(ctx context.Context, binaryName string, candidates []string) (string, error) {
+	resp, err := o.AnalyzeRequestContext(ctx, &AIRequest{
+		BinaryName: binaryName,
+		Candidates: candidates,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Version, nil
+}
+
+// AnalyzeRequest implements the AIProvider interface
+func (o *OllamaProvider) AnalyzeRequest(request *AIRequest) (*AIResponse, error) {
+	return o.AnalyzeRequestContext(context.Background(), request)
+}
+
+// AnalyzeRequestContext implements the AIProvider interface. The model is asked for
+// a JSON object through Ollama's format option.
+func (o *OllamaProvider) AnalyzeRequestContext(ctx context.Context, request *AIRequest) (*AIResponse, error) {
+	if len(request.Candidates) == 0 {
+		return nil, fmt.Errorf("no version candidates provided")
+	}
+
+	reqBody := OllamaRequest{
+		Model: o.config.Model,
+		Messages: []Message{
+			{
+				Role:    "system",
+				Content: analysisSystemPrompt,
+			},
+			{
+				Role:    "user",
+				Content: buildAnalysisPrompt(request),
+			},
+		},
+		Stream: false,
+		Format: "json",
+		Options: OllamaOptions{
+			Temperature: o.config.Temperature,
+			NumPredict:  o.config.MaxTokens,
+		},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/api/chat", strings.TrimSuffix(o.config.BaseURL, "/"))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	meter := UsageMeterFromContext(ctx)
+	if err := meter.Allow(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request to Ollama at %s: %v", o.config.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %v", err)
+	}
+
+	var ollamaResp OllamaResponse
+	decodeErr := json.Unmarshal(body, &ollamaResp)
+
+	if resp.StatusCode != http.StatusOK {
+		if decodeErr == nil && ollamaResp.Error != "" {
+			return nil, o.apiError(resp.StatusCode, ollamaResp.Error)
+		}
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("error decoding response: %v", decodeErr)
+	}
+	if ollamaResp.Error != "" {
+		return nil, o.apiError(resp.StatusCode, ollamaResp.Error)
+	}
+	usage := meter.Record(o.config, ollamaResp.PromptEvalCount, ollamaResp.EvalCount, time.Since(start))
+
+	aiResp, err := parseAIResponse(ollamaResp.Message.Content, o.GetProviderName())
+	if err != nil {
+		return nil, err
+	}
+	aiResp.Usage = &usage
+	return aiResp, nil
+}
+
+// apiError converts an error message from the daemon, recognizing models
+// that still have to be pulled
+func (o *OllamaProvider) apiError(status int, message string) error {
+	if status == http.StatusNotFound || strings.Contains(message, "try pulling it first") {
+		return &OllamaModelError{Model: o.config.Model, Message: message}
+	}
+	return fmt.Errorf("request to Ollama failed with status %d: %s", status, message)
+}
+
+// GetProviderName returns the name of the provider
+func (o *OllamaProvider) GetProviderName() string {
+	return "Ollama"
+}
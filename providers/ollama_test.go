@@ -0,0 +1,147 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOllamaAnalyzeRequest(t *testing.T) {
+	config := newOllamaStandIn(t, func(w http.ResponseWriter, req OllamaRequest) {
+		if req.Model != "llama3.2" {
+			t.Errorf("model = %q, want llama3.2", req.Model)
+		}
+		if req.Stream {
+			t.Error("stream must be disabled")
+		}
+		if req.Format != "json" {
+			t.Errorf("format = %q, want json", req.Format)
+		}
+		if len(req.Messages) != 2 || req.Messages[0].Role != "system" {
+			t.Errorf("unexpected messages: %+v", req.Messages)
+		}
+
+		json.NewEncoder(w).Encode(OllamaResponse{
+			Model: req.Model,
+			Message: Message{
+				Role:    "assistant",
+				Content: `{"version": "7.88.1", "confidence": 0.9, "product": "curl", "rationale": "Appears next to the curl name"}`,
+			},
+			Done: true,
+		})
+	})
+
+	resp, err := NewOllamaProvider(config).AnalyzeRequest(&AIRequest{
+		BinaryName: "curl",
+		Candidates: []string{"7.88.1", "3.0.17"},
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeRequest: %v", err)
+	}
+
+	if resp.Version != "7.88.1" || resp.Product != "curl" || resp.Confidence != 0.9 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.ProviderName != "Ollama" {
+		t.Errorf("provider name = %q, want Ollama", resp.ProviderName)
+	}
+}
+
+func TestOllamaUnknownVersion(t *testing.T) {
+	var calls int
+	config := newOllamaStandIn(t, func(w http.ResponseWriter, req OllamaRequest) {
+		calls++
+		if !strings.Contains(req.Messages[0].Content, `"version": "unknown"`) {
+			t.Error("system prompt does not offer the unknown answer")
+		}
+		json.NewEncoder(w).Encode(OllamaResponse{
+			Model: req.Model,
+			Message: Message{
+				Role:    "assistant",
+				Content: `{"version": "Unknown", "confidence": 0, "product": "", "rationale": "Only glibc versions"}`,
+			},
+			Done: true,
+		})
+	})
+
+	provider := NewValidatingProvider(NewOllamaProvider(config), DefaultValidationRetries)
+	resp, err := provider.AnalyzeRequest(&AIRequest{BinaryName: "ls", Candidates: []string{"2.34", "2.2.5"}})
+	if err != nil {
+		t.Fatalf("AnalyzeRequest: %v", err)
+	}
+	if resp.Version != UnknownVersion {
+		t.Errorf("version = %q, want %q", resp.Version, UnknownVersion)
+	}
+	if calls != 1 {
+		t.Errorf("requests = %d, want 1 since an unknown answer is not re-prompted", calls)
+	}
+}
+
+func TestOllamaModelNotPulled(t *testing.T) {
+	config := newOllamaStandIn(t, func(w http.ResponseWriter, req OllamaRequest) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": `model "` + req.Model + `" not found, try pulling it first`,
+		})
+	})
+	config.Model = "qwen2.5"
+
+	_, err := NewOllamaProvider(config).AnalyzeVersions("curl", []string{"7.88.1"})
+
+	var modelErr *OllamaModelError
+	if !errors.As(err, &modelErr) {
+		t.Fatalf("error = %v, want OllamaModelError", err)
+	}
+	if modelErr.Model != "qwen2.5" {
+		t.Errorf("model = %q, want qwen2.5", modelErr.Model)
+	}
+}
+
+func TestOllamaServerError(t *testing.T) {
+	config := newOllamaStandIn(t, func(w http.ResponseWriter, req OllamaRequest) {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "out of memory"})
+	})
+
+	_, err := NewOllamaProvider(config).AnalyzeVersions("curl", []string{"7.88.1"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var modelErr *OllamaModelError
+	if errors.As(err, &modelErr) {
+		t.Errorf("server errors must not be reported as missing models: %v", err)
+	}
+}
+
+func TestOllamaNeedsNoAPIKey(t *testing.T) {
+	config := DefaultConfigs[ProviderOllama]
+	if err := ValidateConfig(&config); err != nil {
+		t.Fatalf("ValidateConfig: %v", err)
+	}
+
+	provider, err := NewAIFactory().CreateProvider(&config)
+	if err != nil {
+		t.Fatalf("CreateProvider: %v", err)
+	}
+	if provider.GetProviderName() != "Ollama" {
+		t.Errorf("provider name = %q, want Ollama", provider.GetProviderName())
+	}
+}
+
+func TestOllamaHostFromEnv(t *testing.T) {
+	t.Setenv("AI_PROVIDER", "ollama")
+	t.Setenv("AI_OFFLINE", "")
+	t.Setenv("AI_BASE_URL", "")
+	t.Setenv("OLLAMA_HOST", "127.0.0.1:11500")
+
+	config, err := LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("LoadConfigFromEnv: %v", err)
+	}
+	if config.BaseURL != "http://127.0.0.1:11500" {
+		t.Errorf("base URL = %q, want http://127.0.0.1:11500", config.BaseURL)
+	}
+}
@@ -3,13 +3,13 @@ package providers
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
 )
 
-// OpenAIProvider implements the AIProvider interface for OpenAI API
+// OpenAIProvider implements the AIProvider interface for the OpenAI API and
+// for Azure OpenAI deployments
 type OpenAIProvider struct {
 	config *AIConfig
 	client *openai.Client
@@ -17,22 +17,39 @@ type OpenAIProvider struct {
 
 // NewOpenAIProvider creates a new OpenAI provider with configuration
 func NewOpenAIProvider(config *AIConfig) *OpenAIProvider {
-	clientConfig := openai.DefaultConfig(config.APIKey)
-
-	// Set custom base URL if provided
-	if config.BaseURL != "" {
-		clientConfig.BaseURL = config.BaseURL
+	return &OpenAIProvider{
+		config: config,
+		client: openai.NewClientWithConfig(newOpenAIClientConfig(config)),
 	}
+}
 
-	// Set timeout
-	clientConfig.HTTPClient.Timeout = time.Duration(config.Timeout) * time.Second
+// newOpenAIClientConfig creates the go-openai client configuration
+func newOpenAIClientConfig(config *AIConfig) openai.ClientConfig {
+	var clientConfig openai.ClientConfig
+	if config.Provider == ProviderAzure {
+		// Azure routes by deployment and authenticates with the api-key header
+		clientConfig = openai.DefaultAzureConfig(config.APIKey, config.BaseURL)
+		if config.APIVersion != "" {
+			clientConfig.APIVersion = config.APIVersion
+		}
+		deployment := azureDeployment(config)
+		clientConfig.AzureModelMapperFunc = func(model string) string {
+			return deployment
+		}
+	} else {
+		clientConfig = openai.DefaultConfig(config.APIKey)
+
+		// Set custom base URL if provided
+		if config.BaseURL != "" {
+			clientConfig.BaseURL = config.BaseURL
+		}
+		clientConfig.OrgID = config.OrgID
+	}
 
-	client := openai.NewClientWithConfig(clientConfig)
+	// Retries and timeouts are handled by the shared transport
+	clientConfig.HTTPClient = newHTTPClient(config)
 
-	return &OpenAIProvider{
-		config: config,
-		client: client,
-	}
+	return clientConfig
 }
 
 // GetConfig returns the current configuration
@@ -47,14 +64,8 @@ func (o *OpenAIProvider) UpdateConfig(config *AIConfig) error {
 	}
 
 	// Create new client with updated config
-	clientConfig := openai.DefaultConfig(config.APIKey)
-	if config.BaseURL != "" {
-		clientConfig.BaseURL = config.BaseURL
-	}
-	clientConfig.HTTPClient.Timeout = time.Duration(config.Timeout) * time.Second
-
 	o.config = config
-	o.client = openai.NewClientWithConfig(clientConfig)
+	o.client = openai.NewClientWithConfig(newOpenAIClientConfig(config))
 	return nil
 }
 
@@ -75,55 +86,87 @@ func (o *OpenAIProvider) SetMaxTokens(tokens int) {
 
 // AnalyzeVersions implements the AIProvider interface
 func (o *OpenAIProvider) AnalyzeVersions(binaryName string, candidates []string) (string, error) {
-	if len(candidates) == 0 {
-		return "", fmt.Errorf("no version candidates provided")
+	return o.AnalyzeVersionsContext(context.Background(), binaryName, candidates)
+}
+
+// AnalyzeVersionsContext implements the AIProvider interface
+func (o *OpenAIProvider) AnalyzeVersionsContext(ctx context.Context, binaryName string, candidates []string) (string, error) {
+	resp, err := o.AnalyzeRequestContext(ctx, &AIRequest{
+		BinaryName: binaryName,
+		Candidates: candidates,
+	})
+	if err != nil {
+		return "", err
 	}
+	return resp.Version, nil
+}
+
+// AnalyzeRequest implements the AIProvider interface
+func (o *OpenAIProvider) AnalyzeRequest(request *AIRequest) (*AIResponse, error) {
+	return o.AnalyzeRequestContext(context.Background(), request)
+}
 
-	prompt := o.buildPrompt(binaryName, candidates)
+// AnalyzeRequestContext implements the AIProvider interface. The model is asked for
+// a JSON object through response_format.
+func (o *OpenAIProvider) AnalyzeRequestContext(ctx context.Context, request *AIRequest) (*AIResponse, error) {
+	if len(request.Candidates) == 0 {
+		return nil, fmt.Errorf("no version candidates provided")
+	}
 
 	req := openai.ChatCompletionRequest{
 		Model: o.config.Model,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are a version number analyzer. Your task is to identify the most likely semantic version from a list of candidates. Respond with only the version number, nothing else.",
+				Content: analysisSystemPrompt,
 			},
 			{
 				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
+				Content: buildAnalysisPrompt(request),
 			},
 		},
 		MaxTokens:   o.config.MaxTokens,
 		Temperature: float32(o.config.Temperature),
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(o.config.Timeout)*time.Second)
-	defer cancel()
-
+	meter := UsageMeterFromContext(ctx)
+	if err := meter.Allow(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
 	resp, err := o.client.CreateChatCompletion(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("error calling OpenAI API: %v", err)
+		return nil, fmt.Errorf("error calling %s API: %v", o.GetProviderName(), err)
 	}
+	usage := meter.Record(o.config, resp.Usage.PromptTokens, resp.Usage.CompletionTokens, time.Since(start))
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI API")
+		return nil, fmt.Errorf("no response from %s API", o.GetProviderName())
 	}
 
-	version := strings.TrimSpace(resp.Choices[0].Message.Content)
-	return version, nil
+	aiResp, err := parseAIResponse(resp.Choices[0].Message.Content, o.GetProviderName())
+	if err != nil {
+		return nil, err
+	}
+	aiResp.Usage = &usage
+	return aiResp, nil
 }
 
 // GetProviderName returns the name of the provider
 func (o *OpenAIProvider) GetProviderName() string {
+	if o.config.Provider == ProviderAzure {
+		return "Azure OpenAI"
+	}
 	return "OpenAI"
 }
 
-// buildPrompt creates the prompt for version analysis
-func (o *OpenAIProvider) buildPrompt(binaryName string, candidates []string) string {
-	return fmt.Sprintf(`Given the following candidate strings, identify the most likely semantic version for the %s binary. Ignore unrelated floats or library dependencies.
-
-Candidates:
-%s
-
-Please provide only the most likely version number in your response, nothing else.`, binaryName, "- "+strings.Join(candidates, "\n- "))
+// azureDeployment returns the Azure deployment name, which defaults to the model
+func azureDeployment(config *AIConfig) string {
+	if config.Deployment != "" {
+		return config.Deployment
+	}
+	return config.Model
 }
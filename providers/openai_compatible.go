@@ -0,0 +1,205 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAICompatibleProvider implements the AIProvider interface for self-hosted
+// gateways that speak the OpenAI chat completions API, such as vLLM or the
+// llama.cpp server. Headers, authentication, request path and extra body
+// parameters all come from the configuration.
+type OpenAICompatibleProvider struct {
+	config *AIConfig
+	client *http.Client
+}
+
+// NewOpenAICompatibleProvider creates a new OpenAI-compatible provider with configuration
+func NewOpenAICompatibleProvider(config *AIConfig) *OpenAICompatibleProvider {
+	return &OpenAICompatibleProvider{
+		config: config,
+		client: newHTTPClient(config),
+	}
+}
+
+// GetConfig returns the current configuration
+func (o *OpenAICompatibleProvider) GetConfig() *AIConfig {
+	return o.config
+}
+
+// UpdateConfig updates the provider configuration
+func (o *OpenAICompatibleProvider) UpdateConfig(config *AIConfig) error {
+	if err := ValidateConfig(config); err != nil {
+		return err
+	}
+	o.config = config
+	o.client = newHTTPClient(config)
+	return nil
+}
+
+// AnalyzeVersions implements the AIProvider interface
+func (o *OpenAICompatibleProvider) AnalyzeVersions(binaryName string, candidates []string) (string, error) {
+	return o.AnalyzeVersionsContext(context.Background(), binaryName, candidates)
+}
+
+// AnalyzeVersionsContext implements the AIProvider interface
+func (o *OpenAICompatibleProvider) AnalyzeVersionsContext(ctx context.Context, binaryName string, candidates []string) (string, error) {
+	resp, err := o.AnalyzeRequestContext(ctx, &AIRequest{
+		BinaryName: binaryName,
+		Candidates: candidates,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Version, nil
+}
+
+// AnalyzeRequest implements the AIProvider interface
+func (o *OpenAICompatibleProvider) AnalyzeRequest(request *AIRequest) (*AIResponse, error) {
+	return o.AnalyzeRequestContext(context.Background(), request)
+}
+
+// AnalyzeRequestContext implements the AIProvider interface
+func (o *OpenAICompatibleProvider) AnalyzeRequestContext(ctx context.Context, request *AIRequest) (*AIResponse, error) {
+	if len(request.Candidates) == 0 {
+		return nil, fmt.Errorf("no version candidates provided")
+	}
+
+	body, err := o.buildBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.endpoint(), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range o.config.Headers {
+		req.Header.Set(name, value)
+	}
+	if o.config.APIKey != "" {
+		req.Header.Set(o.authHeader(), strings.TrimSpace(o.config.AuthPrefix+" "+o.config.APIKey))
+	}
+
+	meter := UsageMeterFromContext(ctx)
+	if err := meter.Allow(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var chatResp GroqResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+	usage := meter.Record(o.config, chatResp.Usage.PromptTokens, chatResp.Usage.CompletionTokens, time.Since(start))
+
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from %s", o.config.BaseURL)
+	}
+
+	aiResp, err := parseAIResponse(chatResp.Choices[0].Message.Content, o.GetProviderName())
+	if err != nil {
+		return nil, err
+	}
+	aiResp.Usage = &usage
+	return aiResp, nil
+}
+
+// buildBody creates the request body. Extra body parameters are merged in
+// last, so they can override defaults; a null value removes a field, for
+// servers that reject response_format for example.
+func (o *OpenAICompatibleProvider) buildBody(request *AIRequest) ([]byte, error) {
+	body := map[string]interface{}{
+		"model": o.config.Model,
+		"messages": []Message{
+			{Role: "system", Content: analysisSystemPrompt},
+			{Role: "user", Content: buildAnalysisPrompt(request)},
+		},
+		"max_tokens":      o.config.MaxTokens,
+		"temperature":     o.config.Temperature,
+		"response_format": ResponseFormat{Type: "json_object"},
+	}
+
+	for key, value := range o.config.ExtraBody {
+		if key == "messages" {
+			continue
+		}
+		if value == nil {
+			delete(body, key)
+			continue
+		}
+		body[key] = jsonCompatible(value)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+	return data, nil
+}
+
+// endpoint joins the base URL and the chat completions path
+func (o *OpenAICompatibleProvider) endpoint() string {
+	path := o.config.ChatPath
+	if path == "" {
+		path = "/v1/chat/completions"
+	}
+	return strings.TrimSuffix(o.config.BaseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (o *OpenAICompatibleProvider) authHeader() string {
+	if o.config.AuthHeader == "" {
+		return "Authorization"
+	}
+	return o.config.AuthHeader
+}
+
+// GetProviderName returns the name of the provider
+func (o *OpenAICompatibleProvider) GetProviderName() string {
+	return "OpenAI-compatible"
+}
+
+// jsonCompatible converts the map[interface{}]interface{} values produced by
+// the YAML decoder into maps that encoding/json can marshal
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return converted
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = jsonCompatible(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = jsonCompatible(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
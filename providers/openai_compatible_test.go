@@ -0,0 +1,106 @@
+package providers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOpenAICompatibleAnalyzeRequest(t *testing.T) {
+	config, body := newLlamaCppStandIn(t, func(r *http.Request) {
+		if got := r.Header.Get("X-Api-Key"); got != "Token secret" {
+			t.Errorf("X-Api-Key = %q, want %q", got, "Token secret")
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization must not be sent, got %q", got)
+		}
+		if got := r.Header.Get("X-Team"); got != "platform" {
+			t.Errorf("X-Team = %q, want platform", got)
+		}
+	})
+	config.APIKey = "secret"
+	config.AuthHeader = "X-Api-Key"
+	config.AuthPrefix = "Token"
+	config.Headers = map[string]string{"X-Team": "platform"}
+	config.MaxTokens = 8192
+	config.ExtraBody = map[string]interface{}{
+		"cache_prompt":    true,
+		"top_k":           20,
+		"response_format": nil,
+	}
+
+	if err := ValidateConfig(config); err != nil {
+		t.Fatalf("ValidateConfig: %v", err)
+	}
+
+	resp, err := NewOpenAICompatibleProvider(config).AnalyzeRequest(&AIRequest{
+		BinaryName: "git",
+		Candidates: []string{"2.39.5", "1.2.13"},
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeRequest: %v", err)
+	}
+	if resp.Version != "2.39.5" || resp.Product != "git" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	if (*body)["max_tokens"] != float64(8192) {
+		t.Errorf("max_tokens = %v, want 8192", (*body)["max_tokens"])
+	}
+	if (*body)["cache_prompt"] != true || (*body)["top_k"] != float64(20) {
+		t.Errorf("extra body parameters missing: %v", *body)
+	}
+	if _, exists := (*body)["response_format"]; exists {
+		t.Error("response_format should have been removed by a null extra body value")
+	}
+}
+
+func TestOpenAICompatibleDefaultAuth(t *testing.T) {
+	config, body := newLlamaCppStandIn(t, func(r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-local" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer sk-local")
+		}
+	})
+	config.APIKey = "sk-local"
+
+	if _, err := NewOpenAICompatibleProvider(config).AnalyzeVersions("git", []string{"2.39.5"}); err != nil {
+		t.Fatalf("AnalyzeVersions: %v", err)
+	}
+	if _, exists := (*body)["response_format"]; !exists {
+		t.Error("response_format should be sent by default")
+	}
+}
+
+func TestOpenAICompatibleEnv(t *testing.T) {
+	env := map[string]string{
+		"AI_PROVIDER":                   "openai-compatible",
+		"OPENAI_COMPATIBLE_BASE_URL":    "http://gateway:8000",
+		"OPENAI_COMPATIBLE_CHAT_PATH":   "/api/chat/completions",
+		"OPENAI_COMPATIBLE_HEADERS":     "X-Team=platform, X-Env=prod",
+		"OPENAI_COMPATIBLE_EXTRA_BODY":  `{"top_p": 0.9}`,
+		"OPENAI_COMPATIBLE_MAX_TOKENS":  "16000",
+		"OPENAI_COMPATIBLE_AUTH_HEADER": "X-Api-Key",
+	}
+	resolver := NewConfigResolver(nil, "", nil)
+	resolver.LookupEnv = func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	config, _, err := resolver.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+
+	if config.BaseURL != "http://gateway:8000" || config.ChatPath != "/api/chat/completions" {
+		t.Errorf("unexpected endpoint: %s %s", config.BaseURL, config.ChatPath)
+	}
+	if config.Headers["X-Team"] != "platform" || config.Headers["X-Env"] != "prod" {
+		t.Errorf("unexpected headers: %v", config.Headers)
+	}
+	if config.ExtraBody["top_p"] != 0.9 {
+		t.Errorf("unexpected extra body: %v", config.ExtraBody)
+	}
+	if config.MaxTokens != 16000 || config.AuthHeader != "X-Api-Key" {
+		t.Errorf("unexpected config: %+v", config)
+	}
+}
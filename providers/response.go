@@ -0,0 +1,155 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PromptVersion identifies the prompt template in cache keys. Bump it when
+// analysisSystemPrompt or buildAnalysisPrompt change, so cached verdicts of
+// the old prompt are not reused.
+const PromptVersion = "2"
+
+// analysisSystemPrompt asks chat models for a structured JSON answer
+const analysisSystemPrompt = `You are a version number analyzer. Your task is to identify the most likely version of a binary from a list of candidates found in it.
+Respond with a single JSON object and nothing else, using these fields:
+  "version": the most likely version, copied exactly from the candidates
+  "confidence": a number between 0 and 1
+  "product": the name of the product the version belongs to
+  "rationale": one short sentence explaining the choice
+If no candidate is the binary's own version, for example when all of them belong to libraries, the compiler or dates, do not guess. Answer with the version "unknown" instead:
+  {"version": "unknown", "confidence": 0, "product": "", "rationale": "All candidates are library versions"}`
+
+// structuredAnswer is the JSON object models are asked to return
+type structuredAnswer struct {
+	Version    string      `json:"version"`
+	Confidence interface{} `json:"confidence"`
+	Product    string      `json:"product"`
+	Rationale  string      `json:"rationale"`
+}
+
+var (
+	// codeFence matches a markdown code block some models wrap JSON in
+	codeFence = regexp.MustCompile("(?s)```(?:json)?\\s*(.*?)\\s*```")
+	// jsonVersionField recovers the version from truncated or malformed JSON
+	jsonVersionField = regexp.MustCompile(`"version"\s*:\s*"([^"]+)"`)
+)
+
+// buildAnalysisPrompt creates the user prompt for version analysis, including
+// the scan evidence when it is available
+func buildAnalysisPrompt(req *AIRequest) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Given the following candidate strings, identify the most likely version for the %s binary. Ignore unrelated floats or library dependencies.\n\n", req.BinaryName))
+	sb.WriteString("Candidates:\n")
+
+	if len(req.Evidence) > 0 {
+		for _, evidence := range req.Evidence {
+			sb.WriteString(fmt.Sprintf("- %s", evidence.Version))
+			if evidence.Pattern != "" {
+				sb.WriteString(fmt.Sprintf(" (pattern: %s, seen %d time(s))", evidence.Pattern, evidence.Count))
+			}
+			if evidence.Context != "" && evidence.Context != evidence.Version {
+				sb.WriteString(fmt.Sprintf("\n  context: %q", evidence.Context))
+			}
+			sb.WriteString("\n")
+		}
+	} else {
+		for _, candidate := range req.Candidates {
+			sb.WriteString(fmt.Sprintf("- %s\n", candidate))
+		}
+	}
+
+	sb.WriteString("\nRespond with a JSON object containing version, confidence, product and rationale, or with the version \"unknown\" when none of the candidates fits.")
+	if req.Feedback != "" {
+		sb.WriteString("\n\n" + req.Feedback)
+	}
+	return sb.String()
+}
+
+// parseAIResponse extracts the structured answer from a model reply. Replies
+// that are not valid JSON fall back to the version field alone, and plain
+// text replies are taken as the version itself.
+func parseAIResponse(content, providerName string) (*AIResponse, error) {
+	content = strings.TrimSpace(content)
+	if match := codeFence.FindStringSubmatch(content); match != nil {
+		content = match[1]
+	}
+
+	response := &AIResponse{ProviderName: providerName}
+
+	var answer structuredAnswer
+	if decodeAnswer(content, &answer) {
+		response.Version = strings.TrimSpace(answer.Version)
+		response.Confidence = parseConfidence(answer.Confidence)
+		response.Product = strings.TrimSpace(answer.Product)
+		response.Rationale = strings.TrimSpace(answer.Rationale)
+	} else if match := jsonVersionField.FindStringSubmatch(content); match != nil {
+		response.Version = strings.TrimSpace(match[1])
+	} else {
+		response.Version = plainTextVersion(content)
+	}
+
+	// Models capitalize the unknown answer as they please
+	if strings.EqualFold(response.Version, UnknownVersion) {
+		response.Version = UnknownVersion
+	}
+
+	if response.Version == "" {
+		return nil, fmt.Errorf("no version in response from %s: %q", providerName, content)
+	}
+	return response, nil
+}
+
+// decodeAnswer decodes content as JSON, retrying with the outermost object
+// when the model added text around it
+func decodeAnswer(content string, answer *structuredAnswer) bool {
+	if json.Unmarshal([]byte(content), answer) == nil {
+		return true
+	}
+
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return false
+	}
+	return json.Unmarshal([]byte(content[start:end+1]), answer) == nil
+}
+
+// parseConfidence accepts a confidence given as a fraction, a percentage or a string
+func parseConfidence(value interface{}) float64 {
+	var confidence float64
+	switch v := value.(type) {
+	case float64:
+		confidence = v
+	case string:
+		s := strings.TrimSpace(v)
+		percent := strings.HasSuffix(s, "%")
+		parsed, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return 0
+		}
+		confidence = parsed
+		if percent {
+			confidence /= 100
+		}
+	default:
+		return 0
+	}
+
+	if confidence > 1 && confidence <= 100 {
+		confidence /= 100
+	}
+	if confidence < 0 || confidence > 1 {
+		return 0
+	}
+	return confidence
+}
+
+// plainTextVersion takes the first line of a plain text reply as the version
+func plainTextVersion(content string) string {
+	line := strings.TrimSpace(strings.SplitN(content, "\n", 2)[0])
+	return strings.Trim(line, "\"'`")
+}
@@ -0,0 +1,278 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxAttempts is how often a request is sent before giving up
+	DefaultMaxAttempts = 3
+	// DefaultRetryDeadline is the total time in seconds all attempts may take
+	DefaultRetryDeadline = 120
+)
+
+// RetryPolicy decides whether and when a failed request is sent again
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts including the first one
+	Deadline    time.Duration // Total time for all attempts and waits
+	Timeout     time.Duration // Time for a single attempt
+	BaseDelay   time.Duration // Backoff before the second attempt
+	MaxDelay    time.Duration // Backoff ceiling
+}
+
+// NewRetryPolicy creates the retry policy of a provider configuration
+func NewRetryPolicy(config *AIConfig) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: config.MaxAttempts,
+		Deadline:    time.Duration(config.RetryDeadline) * time.Second,
+		Timeout:     time.Duration(config.Timeout) * time.Second,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+// Backoff returns the wait before the given retry (1 for the first retry):
+// exponential growth capped at MaxDelay, with jitter over its upper half so
+// parallel clients spread out
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(jitter(int64(half)+1))
+}
+
+// IsRetryableStatus reports whether a response status is worth retrying.
+// Rate limits, timeouts and server errors are; other client errors such as
+// 400 or 401 will fail the same way again.
+func IsRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	case 529: // Anthropic's overloaded status
+		return true
+	}
+	return status >= 500 && status != http.StatusNotImplemented && status != http.StatusHTTPVersionNotSupported
+}
+
+// RetryAfter reads the server's hint on when to retry: Retry-After in seconds
+// or as a date, retry-after-ms, or the reset time of an exhausted
+// x-ratelimit-* or anthropic-ratelimit-* budget
+func RetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if value := header.Get("retry-after-ms"); value != "" {
+		if ms, err := strconv.ParseFloat(value, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+			return time.Duration(seconds * float64(time.Second)), true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return nonNegative(date.Sub(now)), true
+		}
+	}
+
+	// Wait for the longest exhausted budget to reset
+	var wait time.Duration
+	found := false
+	for _, budget := range []string{"requests", "tokens", "input-tokens", "output-tokens"} {
+		for _, prefix := range []string{"x-ratelimit", "anthropic-ratelimit"} {
+			if strings.TrimSpace(header.Get(prefix+"-remaining-"+budget)) != "0" {
+				continue
+			}
+			if reset, ok := parseRateLimitReset(header.Get(prefix+"-reset-"+budget), now); ok {
+				found = true
+				if reset > wait {
+					wait = reset
+				}
+			}
+		}
+	}
+	return wait, found
+}
+
+// parseRateLimitReset parses a reset hint, either a duration such as "2m59.56s"
+// (Groq, OpenAI) or an RFC 3339 time (Anthropic)
+func parseRateLimitReset(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return nonNegative(d), true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return nonNegative(t.Sub(now)), true
+	}
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// jitter returns a random number in [0, n)
+func jitter(n int64) int64 {
+	if n <= 0 {
+		return 0
+	}
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return jitterRand.Int63n(n)
+}
+
+// retryTransport sends a request again after a retryable failure. Each
+// attempt has its own timeout, and no attempt or wait runs past the deadline.
+// When retries are used up, the last response is returned as is so providers
+// report the server's error.
+type retryTransport struct {
+	base   http.RoundTripper
+	policy RetryPolicy
+}
+
+// newHTTPClient creates the HTTP client providers send requests with. Timeouts
+// are enforced per attempt by the retry transport.
+func newHTTPClient(config *AIConfig) *http.Client {
+	return &http.Client{
+		Transport: &retryTransport{
+			base:   http.DefaultTransport,
+			policy: NewRetryPolicy(config),
+		},
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := withOptionalTimeout(req.Context(), t.policy.Deadline)
+	deadline, hasDeadline := ctx.Deadline()
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.attempt(ctx, req)
+		if resp != nil && !IsRetryableStatus(resp.StatusCode) {
+			return releaseOnClose(resp, cancel), nil
+		}
+		if err != nil && (ctx.Err() != nil || !isRetryableError(err)) {
+			cancel()
+			return nil, err
+		}
+
+		wait := t.policy.Backoff(attempt)
+		if resp != nil {
+			if hint, ok := RetryAfter(resp.Header, time.Now()); ok {
+				wait = hint
+			}
+		}
+
+		if attempt >= t.policy.MaxAttempts || !replayable || (hasDeadline && time.Now().Add(wait).After(deadline)) {
+			if resp != nil {
+				return releaseOnClose(resp, cancel), nil
+			}
+			cancel()
+			return nil, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			cancel()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends the request once with the per-attempt timeout. The returned
+// body releases the attempt's context when it is closed.
+func (t *retryTransport) attempt(ctx context.Context, req *http.Request) (*http.Response, error) {
+	attemptCtx, cancel := withOptionalTimeout(ctx, t.policy.Timeout)
+
+	attemptReq := req.Clone(attemptCtx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		attemptReq.Body = body
+	}
+
+	resp, err := t.base.RoundTrip(attemptReq)
+	if err != nil {
+		if errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+			if ctx.Err() != nil {
+				err = fmt.Errorf("retry deadline of %v exceeded: %w", t.policy.Deadline, err)
+			} else {
+				err = fmt.Errorf("attempt timed out after %v: %w", t.policy.Timeout, err)
+			}
+		}
+		cancel()
+		return nil, err
+	}
+	return releaseOnClose(resp, cancel), nil
+}
+
+// withOptionalTimeout derives a context with a timeout, or without one when
+// timeout is not positive
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// releaseOnClose cancels a context once the response body is closed
+func releaseOnClose(resp *http.Response, cancel context.CancelFunc) *http.Response {
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp
+}
+
+// isRetryableError reports whether a transport error is worth retrying.
+// Connection failures and attempt timeouts are; a cancelled request is not.
+func isRetryableError(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+// cancelOnClose releases a request's contexts once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
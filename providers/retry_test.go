@@ -0,0 +1,206 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransportHonoursRetryAfter(t *testing.T) {
+	// The backoff alone would outlast the test, so only the hint can make it pass
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	req, transport, calls := newRetryStandIn(t, policy, func(w http.ResponseWriter, n int32) {
+		if n == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+}
+
+func TestRetryTransportExhaustsRetries(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	req, transport, calls := newRetryStandIn(t, policy, func(w http.ResponseWriter, n int32) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	})
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want the last response's 503", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestRetryTransportSkipsClientErrors(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	req, transport, calls := newRetryStandIn(t, policy, func(w http.ResponseWriter, n int32) {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+	})
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestRetryTransportCancelledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	req, transport, calls := newRetryStandIn(t, policy, func(w http.ResponseWriter, n int32) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	// Cancel once the first attempt has been answered and the backoff started
+	go func() {
+		for atomic.LoadInt32(calls) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := transport.RoundTrip(req.WithContext(ctx))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RoundTrip kept waiting after the context was cancelled")
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{10, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := policy.Backoff(tt.retry); got < tt.max/2 || got > tt.max {
+				t.Errorf("Backoff(%d) = %v, want between %v and %v", tt.retry, got, tt.max/2, tt.max)
+			}
+		}
+	}
+
+	if got := (RetryPolicy{}).Backoff(1); got != 0 {
+		t.Errorf("Backoff without a base delay = %v, want 0", got)
+	}
+}
+
+func TestIsRetryableStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusNotFound, false},
+		{http.StatusRequestTimeout, true},
+		{http.StatusConflict, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusNotImplemented, false},
+		{http.StatusBadGateway, true},
+		{http.StatusServiceUnavailable, true},
+		{529, true},
+	}
+
+	for _, tt := range tests {
+		if got := IsRetryableStatus(tt.status); got != tt.want {
+			t.Errorf("IsRetryableStatus(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		header  map[string]string
+		want    time.Duration
+		wantHit bool
+	}{
+		{"none", nil, 0, false},
+		{"seconds", map[string]string{"Retry-After": "7"}, 7 * time.Second, true},
+		{"date", map[string]string{"Retry-After": now.Add(30 * time.Second).Format(http.TimeFormat)}, 30 * time.Second, true},
+		{"past date", map[string]string{"Retry-After": now.Add(-time.Minute).Format(http.TimeFormat)}, 0, true},
+		{"milliseconds", map[string]string{"retry-after-ms": "250", "Retry-After": "7"}, 250 * time.Millisecond, true},
+		{"exhausted budget", map[string]string{
+			"x-ratelimit-remaining-requests": "0",
+			"x-ratelimit-reset-requests":     "2m59.5s",
+			"x-ratelimit-remaining-tokens":   "0",
+			"x-ratelimit-reset-tokens":       "1s",
+		}, 2*time.Minute + 59500*time.Millisecond, true},
+		{"budget left", map[string]string{
+			"x-ratelimit-remaining-requests": "10",
+			"x-ratelimit-reset-requests":     "2m",
+		}, 0, false},
+		{"anthropic reset time", map[string]string{
+			"anthropic-ratelimit-remaining-tokens": "0",
+			"anthropic-ratelimit-reset-tokens":     now.Add(45 * time.Second).Format(time.RFC3339),
+		}, 45 * time.Second, true},
+		{"garbage", map[string]string{"Retry-After": "soon"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for key, value := range tt.header {
+				header.Set(key, value)
+			}
+			got, ok := RetryAfter(header, now)
+			if got != tt.want || ok != tt.wantHit {
+				t.Errorf("RetryAfter = %v, %v, want %v, %v", got, ok, tt.want, tt.wantHit)
+			}
+		})
+	}
+}
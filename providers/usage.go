@@ -0,0 +1,231 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Usage counts the tokens, time and estimated cost of provider calls
+type Usage struct {
+	Calls            int     `json:"calls" yaml:"calls"`
+	PromptTokens     int     `json:"prompt_tokens" yaml:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens" yaml:"completion_tokens"`
+	LatencyMS        int64   `json:"latency_ms" yaml:"latency_ms"`
+	Cost             float64 `json:"estimated_cost_usd" yaml:"estimated_cost_usd"`
+	UnpricedCalls    int     `json:"unpriced_calls,omitempty" yaml:"unpriced_calls,omitempty"` // Calls to models missing from the price table
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		Calls:            u.Calls + other.Calls,
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		LatencyMS:        u.LatencyMS + other.LatencyMS,
+		Cost:             u.Cost + other.Cost,
+		UnpricedCalls:    u.UnpricedCalls + other.UnpricedCalls,
+	}
+}
+
+// Sub returns the usage since an earlier snapshot of the same counter
+func (u Usage) Sub(earlier Usage) Usage {
+	return Usage{
+		Calls:            u.Calls - earlier.Calls,
+		PromptTokens:     u.PromptTokens - earlier.PromptTokens,
+		CompletionTokens: u.CompletionTokens - earlier.CompletionTokens,
+		LatencyMS:        u.LatencyMS - earlier.LatencyMS,
+		Cost:             u.Cost - earlier.Cost,
+		UnpricedCalls:    u.UnpricedCalls - earlier.UnpricedCalls,
+	}
+}
+
+// TotalTokens returns the prompt and completion tokens together
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// String summarizes the usage on one line
+func (u Usage) String() string {
+	s := fmt.Sprintf("%d call(s), %d tokens (%d prompt, %d completion), %v, ~$%.4f",
+		u.Calls, u.TotalTokens(), u.PromptTokens, u.CompletionTokens,
+		time.Duration(u.LatencyMS)*time.Millisecond, u.Cost)
+	if u.UnpricedCalls > 0 {
+		s += fmt.Sprintf(" (%d call(s) without a price)", u.UnpricedCalls)
+	}
+	return s
+}
+
+// ModelPrice is the price of a model in USD per million tokens
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// PriceTable maps model names to prices. A model without an exact entry uses
+// the longest entry it starts with, so "gpt-4o-mini-2024-07-18" is priced as
+// "gpt-4o-mini".
+type PriceTable map[string]ModelPrice
+
+// DefaultPrices are list prices of the default models at the time of writing.
+// They only feed estimates; override them under prices in the config file.
+var DefaultPrices = PriceTable{
+	"llama-3.3-70b-versatile": {Input: 0.59, Output: 0.79},
+	"llama-3.1-8b-instant":    {Input: 0.05, Output: 0.08},
+	"gpt-4o-mini":             {Input: 0.15, Output: 0.60},
+	"gpt-4o":                  {Input: 2.50, Output: 10.00},
+	"gpt-4.1-mini":            {Input: 0.40, Output: 1.60},
+	"gpt-4.1":                 {Input: 2.00, Output: 8.00},
+	"claude-3-5-haiku":        {Input: 0.80, Output: 4.00},
+	"claude-3-5-sonnet":       {Input: 3.00, Output: 15.00},
+	"claude-3-7-sonnet":       {Input: 3.00, Output: 15.00},
+	"claude-sonnet-4":         {Input: 3.00, Output: 15.00},
+}
+
+// Merge returns a copy of the table with the entries of overrides added
+func (t PriceTable) Merge(overrides PriceTable) PriceTable {
+	merged := make(PriceTable, len(t)+len(overrides))
+	for model, price := range t {
+		merged[model] = price
+	}
+	for model, price := range overrides {
+		merged[model] = price
+	}
+	return merged
+}
+
+// Lookup finds the price of a model
+func (t PriceTable) Lookup(model string) (ModelPrice, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+
+	// Longest prefix first, so gpt-4o-mini wins over gpt-4o
+	prefixes := make([]string, 0, len(t))
+	for prefix := range t {
+		if strings.HasPrefix(model, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return ModelPrice{}, false
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+	return t[prefixes[0]], true
+}
+
+// Cost estimates the cost of a call in USD
+func (p ModelPrice) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// BudgetExceededError is returned instead of a provider call once the
+// estimated cost of a run has reached its budget
+type BudgetExceededError struct {
+	Budget float64
+	Spent  float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("cost budget of $%.4f reached (~$%.4f spent)", e.Budget, e.Spent)
+}
+
+// UsageMeter adds up the usage of every provider call made with a context
+// that carries it, including validation retries and chain fallbacks, and
+// refuses further calls once the estimated cost reaches the budget
+type UsageMeter struct {
+	mu     sync.Mutex
+	prices PriceTable
+	budget float64 // USD, zero for no budget
+	total  Usage
+}
+
+// NewUsageMeter creates a meter with a price table and a budget in USD. A
+// budget of zero never refuses calls.
+func NewUsageMeter(prices PriceTable, budget float64) *UsageMeter {
+	if prices == nil {
+		prices = DefaultPrices
+	}
+	return &UsageMeter{prices: prices, budget: budget}
+}
+
+type usageMeterKey struct{}
+
+// WithUsageMeter returns a context whose provider calls are recorded by meter
+func WithUsageMeter(ctx context.Context, meter *UsageMeter) context.Context {
+	return context.WithValue(ctx, usageMeterKey{}, meter)
+}
+
+// UsageMeterFromContext returns the meter of a context, or nil
+func UsageMeterFromContext(ctx context.Context) *UsageMeter {
+	meter, _ := ctx.Value(usageMeterKey{}).(*UsageMeter)
+	return meter
+}
+
+// Allow returns a BudgetExceededError once the budget is used up. A nil meter
+// allows every call.
+func (m *UsageMeter) Allow() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.budget > 0 && m.total.Cost >= m.budget {
+		return &BudgetExceededError{Budget: m.budget, Spent: m.total.Cost}
+	}
+	return nil
+}
+
+// Exceeded reports whether the budget is used up
+func (m *UsageMeter) Exceeded() bool {
+	return m.Allow() != nil
+}
+
+// Budget returns the budget in USD, zero when there is none
+func (m *UsageMeter) Budget() float64 {
+	if m == nil {
+		return 0
+	}
+	return m.budget
+}
+
+// Record adds a call to the totals and returns its usage. Models of local
+// servers without an API key are free unless the price table lists them. A
+// nil meter only returns the call's usage, without a cost.
+func (m *UsageMeter) Record(config *AIConfig, promptTokens, completionTokens int, latency time.Duration) Usage {
+	usage := Usage{
+		Calls:            1,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		LatencyMS:        latency.Milliseconds(),
+	}
+	if m == nil {
+		return usage
+	}
+
+	if price, ok := m.prices.Lookup(config.Model); ok {
+		usage.Cost = price.Cost(promptTokens, completionTokens)
+	} else if RequiresAPIKey(config.Provider) || config.APIKey != "" {
+		usage.UnpricedCalls = 1
+	}
+
+	m.mu.Lock()
+	m.total = m.total.Add(usage)
+	m.mu.Unlock()
+	return usage
+}
+
+// Total returns the usage of all recorded calls
+func (m *UsageMeter) Total() Usage {
+	if m == nil {
+		return Usage{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.total
+}
@@ -0,0 +1,167 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"binary-version-analyzer/versions"
+)
+
+// UnknownVersion is reported when no provider answer could be verified
+const UnknownVersion = "unknown"
+
+// DefaultValidationRetries is how often a provider is re-prompted after an
+// answer that is not one of the candidates
+const DefaultValidationRetries = 2
+
+// UnverifiedVersionError is returned when every answer from a provider was
+// rejected because it did not match any candidate
+type UnverifiedVersionError struct {
+	Provider string
+	Answers  []string
+}
+
+func (e *UnverifiedVersionError) Error() string {
+	return fmt.Sprintf("%s did not return one of the candidates after %d attempt(s), last answer: %q",
+		e.Provider, len(e.Answers), e.Answers[len(e.Answers)-1])
+}
+
+// ValidatingProvider wraps a provider and only accepts answers that match one
+// of the candidates it was given, or UnknownVersion. Rejected answers are sent
+// back to the provider as feedback until the retries are used up.
+type ValidatingProvider struct {
+	provider AIProvider
+	retries  int
+}
+
+// NewValidatingProvider creates a validation layer around provider
+func NewValidatingProvider(provider AIProvider, retries int) *ValidatingProvider {
+	if retries < 0 {
+		retries = 0
+	}
+	return &ValidatingProvider{
+		provider: provider,
+		retries:  retries,
+	}
+}
+
+// AnalyzeVersions implements the AIProvider interface
+func (v *ValidatingProvider) AnalyzeVersions(binaryName string, candidates []string) (string, error) {
+	return v.AnalyzeVersionsContext(context.Background(), binaryName, candidates)
+}
+
+// AnalyzeVersionsContext implements the AIProvider interface
+func (v *ValidatingProvider) AnalyzeVersionsContext(ctx context.Context, binaryName string, candidates []string) (string, error) {
+	resp, err := v.AnalyzeRequestContext(ctx, &AIRequest{
+		BinaryName: binaryName,
+		Candidates: candidates,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Version, nil
+}
+
+// AnalyzeRequest implements the AIProvider interface
+func (v *ValidatingProvider) AnalyzeRequest(req *AIRequest) (*AIResponse, error) {
+	return v.AnalyzeRequestContext(context.Background(), req)
+}
+
+// AnalyzeRequestContext implements the AIProvider interface. The returned version is
+// always the candidate as it was passed in, and its usage includes the rejected answers.
+func (v *ValidatingProvider) AnalyzeRequestContext(ctx context.Context, req *AIRequest) (*AIResponse, error) {
+	attempt := *req
+	var answers []string
+	var usage Usage
+
+	for i := 0; i <= v.retries; i++ {
+		resp, err := v.provider.AnalyzeRequestContext(ctx, &attempt)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Usage != nil {
+			usage = usage.Add(*resp.Usage)
+			resp.Usage = &usage
+		}
+
+		// An explicit unknown makes up no version, so it is passed on as well
+		if resp.Version == UnknownVersion {
+			return resp, nil
+		}
+		if candidate, ok := MatchCandidate(resp.Version, req.Candidates); ok {
+			resp.Version = candidate
+			return resp, nil
+		}
+
+		answers = append(answers, resp.Version)
+		attempt.Feedback = rejectionFeedback(resp.Version, req.Candidates)
+	}
+
+	return nil, &UnverifiedVersionError{
+		Provider: v.provider.GetProviderName(),
+		Answers:  answers,
+	}
+}
+
+// GetProviderName returns the name of the wrapped provider
+func (v *ValidatingProvider) GetProviderName() string {
+	return v.provider.GetProviderName()
+}
+
+// MatchCandidate finds the candidate an answer refers to. Answers match when
+// their normalized forms are equal, or when the answer is a sentence that
+// mentions exactly one candidate.
+func MatchCandidate(answer string, candidates []string) (string, bool) {
+	normalized := versions.Normalize(answer)
+	for _, candidate := range candidates {
+		if strings.EqualFold(versions.Normalize(candidate), normalized) {
+			return candidate, true
+		}
+	}
+
+	var mentioned []string
+	for _, candidate := range candidates {
+		if mentionsVersion(answer, candidate) {
+			mentioned = append(mentioned, candidate)
+		}
+	}
+	if len(mentioned) == 1 {
+		return mentioned[0], true
+	}
+	return "", false
+}
+
+// mentionsVersion reports whether text contains version as a whole token,
+// so "3.11" is not found inside "3.11.2"
+func mentionsVersion(text, version string) bool {
+	for start := 0; ; {
+		i := strings.Index(text[start:], version)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(version)
+
+		if (i == 0 || !isVersionChar(text[i-1])) && (end == len(text) || !isVersionChar(text[end]) || isSentenceEnd(text[end:])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+func isVersionChar(c byte) bool {
+	return c == '.' || c == '-' || c == '+' || c == '_' ||
+		(c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isSentenceEnd reports whether rest starts with a full stop that ends the sentence
+func isSentenceEnd(rest string) bool {
+	return rest[0] == '.' && (len(rest) == 1 || rest[1] == ' ' || rest[1] == '\n')
+}
+
+// rejectionFeedback tells the provider why its previous answer was rejected
+func rejectionFeedback(answer string, candidates []string) string {
+	return fmt.Sprintf("Your previous answer %q is not one of the candidates. Answer with exactly one of: %s.",
+		answer, strings.Join(candidates, ", "))
+}
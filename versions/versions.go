@@ -0,0 +1,228 @@
+package versions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Scheme identifies the versioning scheme a version string follows
+type Scheme string
+
+const (
+	SchemeSemVer  Scheme = "semver"  // Semantic Versioning 2.0 (1.2.3-beta+build)
+	SchemePEP440  Scheme = "pep440"  // Python versions (1.0rc1, 2.1.post3)
+	SchemeDebian  Scheme = "debian"  // Debian epoch:upstream-revision (2:8.2.3995-1ubuntu2)
+	SchemeRPM     Scheme = "rpm"     // RPM epoch:version-release (1.8.0-3.el8)
+	SchemeCalVer  Scheme = "calver"  // Calendar versions (2023.12.15, 2022-03-21)
+	SchemeOpenSSL Scheme = "openssl" // Letter-suffixed releases (1.1.1w)
+	SchemeDotted  Scheme = "dotted"  // Plain dotted numbers that fit no stricter scheme (1.2, 10.0.19041.1)
+)
+
+// MaxLength is the longest version string that is accepted
+const MaxLength = 64
+
+// Version represents a parsed and normalized version string
+type Version struct {
+	Original   string // The string as it was found
+	Normalized string // Canonical form used for comparison and reporting
+	Scheme     Scheme // Scheme the version was recognized as
+}
+
+// String returns the normalized version
+func (v *Version) String() string {
+	return v.Normalized
+}
+
+var (
+	openSSLPattern = regexp.MustCompile(`^\d+\.\d+\.\d+[a-z]{1,2}$`)
+
+	calVerPattern        = regexp.MustCompile(`^((?:19|20)\d{2})([.\-_])(0?[1-9]|1[0-2])(?:([.\-_])(0[1-9]|[12]\d|3[01]))?$`)
+	calVerCompactPattern = regexp.MustCompile(`^(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])$`)
+
+	semVerPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+	// Permissive PEP 440 grammar from the specification's appendix
+	pep440Pattern = regexp.MustCompile(`(?i)^(?:(\d+)!)?(\d+(?:\.\d+)*)` +
+		`(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?(\d+)?)?` +
+		`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d+)?)?` +
+		`(?:[-_.]?(dev)[-_.]?(\d+)?)?` +
+		`(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+	dottedPattern = regexp.MustCompile(`^\d+(?:\.\d+)+$`)
+
+	debianPattern = regexp.MustCompile(`^(?:(\d+):)?(\d[A-Za-z0-9.+~]*)(?:-([A-Za-z0-9.+~]+))?$`)
+	rpmPattern    = regexp.MustCompile(`^(?:(\d+):)?(\d[A-Za-z0-9._+~^]*)-([A-Za-z0-9._+~^]+)$`)
+
+	// Release markers that only appear in distribution package versions
+	debianMarkers = regexp.MustCompile(`(?i)ubuntu|deb\d|dfsg|~|build\d`)
+	rpmMarkers    = regexp.MustCompile(`(?i)\.(el|fc|amzn|mga|suse|sles|module)\d`)
+)
+
+// Parse validates a version string, detects its scheme and normalizes it
+func Parse(s string) (*Version, error) {
+	original := s
+	s = trim(s)
+
+	if s == "" || len(s) > MaxLength {
+		return nil, fmt.Errorf("invalid version length: %q", original)
+	}
+	if s[0] < '0' || s[0] > '9' {
+		return nil, fmt.Errorf("version must start with a digit: %q", original)
+	}
+
+	if hasEmptySegment(s) {
+		return nil, fmt.Errorf("empty version segment: %q", original)
+	}
+
+	version := &Version{Original: original, Normalized: s}
+
+	switch {
+	case openSSLPattern.MatchString(s):
+		version.Scheme = SchemeOpenSSL
+	case calVerPattern.MatchString(s):
+		version.Scheme = SchemeCalVer
+		version.Normalized = normalizeCalVer(s)
+	case calVerCompactPattern.MatchString(s):
+		version.Scheme = SchemeCalVer
+	case rpmPattern.MatchString(s) && rpmMarkers.MatchString(s):
+		version.Scheme = SchemeRPM
+	case debianPattern.MatchString(s) && (strings.Contains(s, ":") || debianMarkers.MatchString(s)):
+		version.Scheme = SchemeDebian
+	case semVerPattern.MatchString(s):
+		version.Scheme = SchemeSemVer
+	case isPEP440(s):
+		version.Scheme = SchemePEP440
+		version.Normalized = normalizePEP440(s)
+	case dottedPattern.MatchString(s):
+		version.Scheme = SchemeDotted
+	case strings.Contains(s, "-") && debianPattern.MatchString(s) && strings.Contains(s, "."):
+		// Generic upstream-revision versions such as 2.31-0.4
+		version.Scheme = SchemeDebian
+	default:
+		return nil, fmt.Errorf("unrecognized version format: %q", original)
+	}
+
+	return version, nil
+}
+
+// hasEmptySegment reports whether separators follow each other, as in
+// "1.0.0-alpha..1" or "2.31.-1", which no scheme allows
+func hasEmptySegment(s string) bool {
+	for i := 1; i < len(s); i++ {
+		if isSeparator(s[i-1]) && isSeparator(s[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSeparator reports whether c separates the parts of a version
+func isSeparator(c byte) bool {
+	return strings.IndexByte(".-+~_:", c) >= 0
+}
+
+// IsValid reports whether s is a recognized version string
+func IsValid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// Normalize returns the normalized form of s, or s trimmed if it is not a valid version
+func Normalize(s string) string {
+	if version, err := Parse(s); err == nil {
+		return version.Normalized
+	}
+	return trim(s)
+}
+
+// trim removes surrounding whitespace, a leading "v" and trailing separators
+// that patterns tend to pick up from the surrounding text
+func trim(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 1 && (s[0] == 'v' || s[0] == 'V') && s[1] >= '0' && s[1] <= '9' {
+		s = s[1:]
+	}
+	return strings.TrimRight(s, ".-_+~:")
+}
+
+// isPEP440 reports whether s is a PEP 440 version with at least one part that
+// plain dotted or semantic versions lack (epoch, pre, post, dev or local)
+func isPEP440(s string) bool {
+	match := pep440Pattern.FindStringSubmatch(s)
+	if match == nil {
+		return false
+	}
+
+	// A plain release segment is reported as dotted, not PEP 440
+	return match[1] != "" || match[3] != "" || match[5] != "" || match[6] != "" || match[8] != "" || match[10] != ""
+}
+
+// normalizePEP440 converts a PEP 440 version to its canonical form
+func normalizePEP440(s string) string {
+	match := pep440Pattern.FindStringSubmatch(s)
+
+	var sb strings.Builder
+	if match[1] != "" {
+		sb.WriteString(trimZeros(match[1]) + "!")
+	}
+
+	release := strings.Split(match[2], ".")
+	for i, part := range release {
+		release[i] = trimZeros(part)
+	}
+	sb.WriteString(strings.Join(release, "."))
+
+	if match[3] != "" {
+		label := strings.ToLower(match[3])
+		switch label {
+		case "alpha":
+			label = "a"
+		case "beta":
+			label = "b"
+		case "c", "pre", "preview":
+			label = "rc"
+		}
+		sb.WriteString(label + trimZeros(orZero(match[4])))
+	}
+
+	if match[5] != "" {
+		sb.WriteString(".post" + trimZeros(match[5]))
+	} else if match[6] != "" {
+		sb.WriteString(".post" + trimZeros(orZero(match[7])))
+	}
+
+	if match[8] != "" {
+		sb.WriteString(".dev" + trimZeros(orZero(match[9])))
+	}
+
+	if match[10] != "" {
+		local := strings.NewReplacer("-", ".", "_", ".").Replace(strings.ToLower(match[10]))
+		sb.WriteString("+" + local)
+	}
+
+	return sb.String()
+}
+
+// normalizeCalVer joins the parts of a calendar version with dots
+func normalizeCalVer(s string) string {
+	return strings.NewReplacer("-", ".", "_", ".").Replace(s)
+}
+
+// trimZeros strips leading zeros from a number, keeping at least one digit
+func trimZeros(s string) string {
+	trimmed := strings.TrimLeft(s, "0")
+	if trimmed == "" {
+		return "0"
+	}
+	return trimmed
+}
+
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,81 @@
+package versions
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input      string
+		scheme     Scheme
+		normalized string
+	}{
+		// SemVer 2.0
+		{"1.2.3", SchemeSemVer, "1.2.3"},
+		{"v2.1.0-alpha.1", SchemeSemVer, "2.1.0-alpha.1"},
+		{"1.0.0+20220101", SchemeSemVer, "1.0.0+20220101"},
+		{"3.2.1-beta.2+build.123", SchemeSemVer, "3.2.1-beta.2+build.123"},
+
+		// PEP 440
+		{"1.0rc1", SchemePEP440, "1.0rc1"},
+		{"2.1.post3", SchemePEP440, "2.1.post3"},
+		{"1.0.dev0", SchemePEP440, "1.0.dev0"},
+		{"1!2.0", SchemePEP440, "1!2.0"},
+		{"3.11.0b1", SchemePEP440, "3.11.0b1"},
+		{"1.0-alpha2", SchemePEP440, "1.0a2"},
+
+		// Debian
+		{"2:8.2.3995-1ubuntu2", SchemeDebian, "2:8.2.3995-1ubuntu2"},
+		{"1.2.3-4+deb11u1", SchemeDebian, "1.2.3-4+deb11u1"},
+		{"2.31-0.4", SchemeDebian, "2.31-0.4"},
+
+		// RPM
+		{"1.8.0-3.el8", SchemeRPM, "1.8.0-3.el8"},
+		{"1:2.4.6-25.fc36", SchemeRPM, "1:2.4.6-25.fc36"},
+
+		// CalVer
+		{"2023.12.15", SchemeCalVer, "2023.12.15"},
+		{"2022-03-21", SchemeCalVer, "2022.03.21"},
+		{"20220321", SchemeCalVer, "20220321"},
+
+		// OpenSSL
+		{"1.1.1w", SchemeOpenSSL, "1.1.1w"},
+		{"0.9.8zh", SchemeOpenSSL, "0.9.8zh"},
+
+		// Dotted
+		{"1.2", SchemeDotted, "1.2"},
+		{"10.0.19041.1", SchemeDotted, "10.0.19041.1"},
+		{"1.2.3.", SchemeSemVer, "1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			v, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.input, err)
+			}
+			if v.Scheme != tt.scheme || v.Normalized != tt.normalized {
+				t.Errorf("Parse(%q) = %q (%s), want %q (%s)", tt.input, v.Normalized, v.Scheme, tt.normalized, tt.scheme)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"abc",
+		"v",
+		"1",
+		"1996",           // Bare years from copyright notices
+		"2023",           // Bare years from copyright notices
+		"1.0.0-alpha..1", // Empty pre-release segment
+		"2.31.-1",        // Empty segment before the revision
+		"1..2",           // Empty dotted segment
+		"2:-1",           // Empty upstream version
+		"1.2.3-beta_!",   // Characters no scheme allows
+		"12345678901234567890.1234567890123456789012345678901234567890.1234", // Too long
+	} {
+		if v, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) = %q (%s), want an error", input, v.Normalized, v.Scheme)
+		}
+	}
+}